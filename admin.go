@@ -0,0 +1,172 @@
+package linkage
+
+import (
+	"context"
+	"linkage/proto/admin"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetaAdminCode is the grpc metadata key of the passcode of admin requests
+const MetaAdminCode = "linkage-admin-code"
+
+// adminServer implements admin.AdminServer for linkage
+type adminServer struct {
+	l          *Linkage
+	codeAssert CodeAssert
+}
+
+// authorize checks the passcode of the request, all requests are denied if codeAssert is nil
+func (a *adminServer) authorize(ctx context.Context) error {
+	if a.codeAssert == nil {
+		return status.Error(codes.PermissionDenied, "no admin passcode")
+	}
+
+	code := ""
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok && len(md[MetaAdminCode]) > 0 {
+		code = md[MetaAdminCode][0]
+	}
+	if !a.codeAssert(code) {
+		return status.Error(codes.PermissionDenied, "wrong admin passcode")
+	}
+	return nil
+}
+
+// ListUpstreams implement admin.AdminServer interface
+func (a *adminServer) ListUpstreams(ctx context.Context, req *admin.ListUpstreamsRequest) (*admin.ListUpstreamsResponse, error) {
+	err := a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &admin.ListUpstreamsResponse{
+		IncomeQueued: int64(a.l.pending.len()),
+	}
+	for _, cli := range a.l.clients {
+		state, connectedAt := cli.State()
+		resp.Upstreams = append(resp.Upstreams, &admin.Upstream{
+			Addr:         cli.info.Addr,
+			State:        state,
+			Paused:       cli.Paused(),
+			Topics:       cli.info.Topics,
+			JobsReceived: cli.Received(),
+			ConnectedAt:  unixNano(connectedAt),
+		})
+	}
+	return resp, nil
+}
+
+// ListDownstreams implement admin.AdminServer interface
+func (a *adminServer) ListDownstreams(ctx context.Context, req *admin.ListDownstreamsRequest) (*admin.ListDownstreamsResponse, error) {
+	err := a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := a.l.server
+	resp := &admin.ListDownstreamsResponse{
+		Unrouted: int64(srv.router.unroutedLen()),
+	}
+	for _, d := range srv.downstreams() {
+		ad := &admin.Downstream{
+			Id:          d.id,
+			Peer:        d.peer,
+			Topics:      d.sub.topics,
+			JobsSent:    atomic.LoadUint64(&d.sent),
+			Unacked:     atomic.LoadInt64(&d.unackedN),
+			Queued:      int64(d.sub.queue.len()),
+			Credit:      atomic.LoadInt64(&d.creditN),
+			Compression: d.compression,
+			ConnectedAt: unixNano(d.connectedAt),
+		}
+		if d.identity != nil {
+			ad.Identity = d.identity.Name
+			ad.AuthMethod = d.identity.Method
+		}
+		resp.Downstreams = append(resp.Downstreams, ad)
+	}
+	return resp, nil
+}
+
+// Disconnect implement admin.AdminServer interface
+func (a *adminServer) Disconnect(ctx context.Context, req *admin.DisconnectRequest) (*admin.DisconnectResponse, error) {
+	err := a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := req.GetReason()
+	if reason == "" {
+		reason = "disconnected by admin"
+	}
+	if !a.l.server.disconnect(req.GetId(), reason) {
+		return nil, status.Errorf(codes.NotFound, "no downstream %v", req.GetId())
+	}
+
+	log.WithFields(log.Fields{
+		"id":     req.GetId(),
+		"reason": reason,
+	}).Info("admin disconnects downstream")
+	return &admin.DisconnectResponse{}, nil
+}
+
+// PauseUpstream implement admin.AdminServer interface
+func (a *adminServer) PauseUpstream(ctx context.Context, req *admin.PauseUpstreamRequest) (*admin.PauseUpstreamResponse, error) {
+	err := a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clis, err := a.upstreams(req.GetAddr())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &admin.PauseUpstreamResponse{}
+	for _, cli := range clis {
+		cli.Pause()
+		log.Infof("admin pauses upstream %v", cli.info.Addr)
+		resp.Paused = append(resp.Paused, cli.info.Addr)
+	}
+	return resp, nil
+}
+
+// ResumeUpstream implement admin.AdminServer interface
+func (a *adminServer) ResumeUpstream(ctx context.Context, req *admin.ResumeUpstreamRequest) (*admin.ResumeUpstreamResponse, error) {
+	err := a.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clis, err := a.upstreams(req.GetAddr())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &admin.ResumeUpstreamResponse{}
+	for _, cli := range clis {
+		cli.Resume()
+		log.Infof("admin resumes upstream %v", cli.info.Addr)
+		resp.Resumed = append(resp.Resumed, cli.info.Addr)
+	}
+	return resp, nil
+}
+
+// upstreams returns the clients of addr, all clients if addr is empty
+func (a *adminServer) upstreams(addr string) ([]*Client, error) {
+	if addr == "" {
+		return a.l.clients, nil
+	}
+
+	for _, cli := range a.l.clients {
+		if cli.info.Addr == addr {
+			return []*Client{cli}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no upstream %v", addr)
+}
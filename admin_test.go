@@ -0,0 +1,125 @@
+package linkage
+
+import (
+	"context"
+	"linkage/proto/admin"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdmin(t *testing.T) {
+	up := newQueueEngine()
+	upAddr := serveUpstream(t, up)
+
+	e := newCollectEngine()
+	addr := freeAddr(t)
+	l, err := InitLinkage(addr, e, nil, func(Code) bool { return true }, []*DialInfo{{
+		Addr: upAddr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	}}, nil, WithAdmin(func(code Code) bool { return code == "admin" }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Run()
+	defer l.Stop()
+	waitListening(t, addr)
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ac := admin.NewAdminClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), MetaAdminCode, "admin")
+
+	_, err = ac.ListUpstreams(context.Background(), &admin.ListUpstreamsRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got error %v without passcode, want permission denied", err)
+	}
+
+	// jobs are not asked once the upstream is paused,
+	// the one being asked when paused may still come
+	_, err = ac.PauseUpstream(ctx, &admin.PauseUpstreamRequest{Addr: upAddr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ups, err := ac.ListUpstreams(ctx, &admin.ListUpstreamsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ups.Upstreams) != 1 || ups.Upstreams[0].Addr != upAddr || !ups.Upstreams[0].Paused {
+		t.Fatalf("got upstreams %v", ups.Upstreams)
+	}
+	up.jobs <- CreateJob("1", nil)
+	up.jobs <- CreateJob("2", nil)
+	time.Sleep(300 * time.Millisecond)
+	if n := len(e.got); n > 1 {
+		t.Fatalf("%v jobs asked while paused", n)
+	}
+
+	_, err = ac.ResumeUpstream(ctx, &admin.ResumeUpstreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.wait(t, time.Second)
+	e.wait(t, time.Second)
+
+	_, err = ac.PauseUpstream(ctx, &admin.PauseUpstreamRequest{Addr: "nowhere:1"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got error %v pausing unknown upstream, want not found", err)
+	}
+
+	// the downstream is listed until it is disconnected
+	c := dialTest(t, &DialInfo{
+		Addr: addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	})
+	defer c.Close()
+	var downs *admin.ListDownstreamsResponse
+	for i := 0; i < 50; i++ {
+		downs, err = ac.ListDownstreams(ctx, &admin.ListDownstreamsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(downs.Downstreams) == 1 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(downs.Downstreams) != 1 {
+		t.Fatalf("got %v downstreams, want 1", len(downs.Downstreams))
+	}
+
+	_, err = ac.Disconnect(ctx, &admin.DisconnectRequest{Id: downs.Downstreams[0].Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Ask(); err == nil {
+		t.Fatal("stream not ended by disconnect")
+	}
+	for i := 0; i < 50; i++ {
+		downs, err = ac.ListDownstreams(ctx, &admin.ListDownstreamsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(downs.Downstreams) == 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("downstream listed after disconnected")
+}
+
+func TestAdminDenied(t *testing.T) {
+	// admin requests are denied without codeAssert
+	a := &adminServer{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetaAdminCode, ""))
+	if err := a.authorize(ctx); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got error %v, want permission denied", err)
+	}
+}
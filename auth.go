@@ -0,0 +1,259 @@
+package linkage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// metadata keys of the credentials
+const (
+	authorizationKey = "authorization"
+	apiKeyKey        = "x-api-key"
+)
+
+// Identity is who the downstream is
+// Method is how it is authenticated, such as "passcode", "mtls", "hmac" and "apikey".
+// The Name of passcode is "passcode:" and the first 8 hex digits of sha256 of the passcode
+type Identity struct {
+	Name   string
+	Method string
+	Attrs  map[string]string
+}
+
+// AuthInfo is what an Authenticator knows about the downstream
+// TLS is nil if the connection is not over TLS
+type AuthInfo struct {
+	Code     Code
+	Peer     *peer.Peer
+	Metadata metadata.MD
+	TLS      *tls.ConnectionState
+}
+
+// Authenticator authenticates the downstream and returns its identity
+type Authenticator interface {
+	Authenticate(info *AuthInfo) (*Identity, error)
+}
+
+// IdentityEngine is an Engine which wants to know who registers
+type IdentityEngine interface {
+	Engine
+	// RegisterIdentity is called instead of Register with the identity of downstream
+	RegisterIdentity(id *Identity, sig chan Signal) (<-chan *Job, error)
+}
+
+func newAuthInfo(ctx context.Context, code Code) *AuthInfo {
+	info := &AuthInfo{
+		Code: code,
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		info.Peer = p
+		if ti, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			info.TLS = &ti.State
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		info.Metadata = md
+	}
+	return info
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	vs := md.Get(key)
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[0]
+}
+
+// TLSAuthenticator identifies the downstream by its verified client certificate,
+// the server should be configured to verify client certificates.
+// Names limits the common names allowed, any verified name is allowed if it is empty
+type TLSAuthenticator struct {
+	Names []string
+}
+
+// Authenticate implements Authenticator
+func (a *TLSAuthenticator) Authenticate(info *AuthInfo) (*Identity, error) {
+	if info.TLS == nil || len(info.TLS.VerifiedChains) == 0 || len(info.TLS.VerifiedChains[0]) == 0 {
+		return nil, errors.New("no verified client certificate")
+	}
+
+	cert := info.TLS.VerifiedChains[0][0]
+	name := cert.Subject.CommonName
+	if len(a.Names) > 0 && !contains(a.Names, name) {
+		return nil, fmt.Errorf("certificate name %v not allowed", name)
+	}
+
+	return &Identity{
+		Name:   name,
+		Method: "mtls",
+		Attrs: map[string]string{
+			"serial": cert.SerialNumber.String(),
+		},
+	}, nil
+}
+
+// HMACAuthenticator checks the token signed by SignToken with the same secret.
+// The token is sent in metadata "authorization" as "Bearer <token>",
+// and expires after MaxAge, token never expires if MaxAge is 0.
+// Token signed later than Skew from now is rejected, default Skew is a minute
+type HMACAuthenticator struct {
+	Secret []byte
+	MaxAge time.Duration
+	Skew   time.Duration
+}
+
+// defaultTokenSkew is the Skew if it is not set
+const defaultTokenSkew = time.Minute
+
+// Authenticate implements Authenticator
+func (a *HMACAuthenticator) Authenticate(info *AuthInfo) (*Identity, error) {
+	token := strings.TrimPrefix(firstMetadata(info.Metadata, authorizationKey), "Bearer ")
+	// name may have dots, time and signature don't
+	name, ts, sig, ok := splitToken(token)
+	if !ok {
+		return nil, errors.New("malformed token")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(sign(a.Secret, name, ts))) {
+		return nil, errors.New("invalid token signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errors.New("malformed token time")
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if -age > a.skew() {
+		return nil, errors.New("token signed in the future")
+	}
+	if a.MaxAge > 0 && age > a.MaxAge {
+		return nil, errors.New("token expired")
+	}
+
+	return &Identity{
+		Name:   name,
+		Method: "hmac",
+	}, nil
+}
+
+func (a *HMACAuthenticator) skew() time.Duration {
+	if a.Skew <= 0 {
+		return defaultTokenSkew
+	}
+	return a.Skew
+}
+
+// splitToken splits the token into name, time and signature by the last two dots
+func splitToken(token string) (string, string, string, bool) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", "", "", false
+	}
+	k := strings.LastIndex(token[:i], ".")
+	if k < 1 {
+		return "", "", "", false
+	}
+	return token[:k], token[k+1 : i], token[i+1:], true
+}
+
+// SignToken returns the token of name signed at t with secret
+func SignToken(secret []byte, name string, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return name + "." + ts + "." + sign(secret, name, ts)
+}
+
+func sign(secret []byte, name string, ts string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(name + "." + ts))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// APIKeyAuthenticator checks the key sent in metadata "x-api-key",
+// Keys maps the key to the name of the downstream
+type APIKeyAuthenticator struct {
+	Keys map[string]string
+}
+
+// Authenticate implements Authenticator
+func (a *APIKeyAuthenticator) Authenticate(info *AuthInfo) (*Identity, error) {
+	key := firstMetadata(info.Metadata, apiKeyKey)
+	if key == "" {
+		return nil, errors.New("no api key")
+	}
+
+	for k, name := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return &Identity{
+				Name:   name,
+				Method: "apikey",
+			}, nil
+		}
+	}
+	return nil, errors.New("invalid api key")
+}
+
+// HMACToken returns the credentials sends a new token signed with secret
+// on each call, use it in DialInfo.Opts by grpc.WithPerRPCCredentials
+func HMACToken(secret []byte, name string) credentials.PerRPCCredentials {
+	return hmacToken{
+		secret: secret,
+		name:   name,
+	}
+}
+
+type hmacToken struct {
+	secret []byte
+	name   string
+}
+
+func (t hmacToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		authorizationKey: "Bearer " + SignToken(t.secret, t.name, time.Now()),
+	}, nil
+}
+
+func (t hmacToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// APIKey returns the credentials sends the key,
+// use it in DialInfo.Opts by grpc.WithPerRPCCredentials
+func APIKey(key string) credentials.PerRPCCredentials {
+	return apiKey(key)
+}
+
+type apiKey string
+
+func (k apiKey) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		apiKeyKey: string(k),
+	}, nil
+}
+
+func (k apiKey) RequireTransportSecurity() bool {
+	return false
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
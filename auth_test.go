@@ -0,0 +1,191 @@
+package linkage
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestHMACAuthenticator(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	cases := []struct {
+		name   string
+		token  string
+		maxAge time.Duration
+		want   string
+	}{
+		{"valid", SignToken(secret, "node", now), time.Minute, "node"},
+		{"dotted name", SignToken(secret, "node.eu.1", now), time.Minute, "node.eu.1"},
+		{"wrong secret", SignToken([]byte("other"), "node", now), time.Minute, ""},
+		{"expired", SignToken(secret, "node", now.Add(-2*time.Minute)), time.Minute, ""},
+		{"never expires", SignToken(secret, "node", now.Add(-time.Hour)), 0, "node"},
+		{"within skew", SignToken(secret, "node", now.Add(30*time.Second)), time.Minute, "node"},
+		{"future", SignToken(secret, "node", now.Add(2*time.Minute)), time.Minute, ""},
+		{"future never expires", SignToken(secret, "node", now.Add(time.Hour)), 0, ""},
+		{"malformed", "node.123", time.Minute, ""},
+		{"no name", ".123.abc", time.Minute, ""},
+		{"no token", "", time.Minute, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &HMACAuthenticator{Secret: secret, MaxAge: tc.maxAge}
+			id, err := a.Authenticate(&AuthInfo{
+				Metadata: metadata.Pairs(authorizationKey, "Bearer "+tc.token),
+			})
+			if ok := tc.want != ""; ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, ok)
+			}
+			if err == nil && (id.Name != tc.want || id.Method != "hmac") {
+				t.Fatalf("got identity %+v, want %v", id, tc.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	a := &APIKeyAuthenticator{Keys: map[string]string{"k1": "node"}}
+	cases := []struct {
+		name string
+		md   metadata.MD
+		ok   bool
+	}{
+		{"valid", metadata.Pairs(apiKeyKey, "k1"), true},
+		{"wrong key", metadata.Pairs(apiKeyKey, "k2"), false},
+		{"no key", metadata.MD{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := a.Authenticate(&AuthInfo{Metadata: tc.md})
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+			if tc.ok && (id.Name != "node" || id.Method != "apikey") {
+				t.Fatalf("got identity %+v", id)
+			}
+		})
+	}
+}
+
+func TestTLSAuthenticator(t *testing.T) {
+	verified := &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{
+			Subject:      pkix.Name{CommonName: "node"},
+			SerialNumber: big.NewInt(7),
+		}}},
+	}
+	cases := []struct {
+		name  string
+		names []string
+		state *tls.ConnectionState
+		ok    bool
+	}{
+		{"any name", nil, verified, true},
+		{"name allowed", []string{"node"}, verified, true},
+		{"name not allowed", []string{"other"}, verified, false},
+		{"not verified", nil, &tls.ConnectionState{}, false},
+		{"no tls", nil, nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &TLSAuthenticator{Names: tc.names}
+			id, err := a.Authenticate(&AuthInfo{TLS: tc.state})
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+			if tc.ok && (id.Name != "node" || id.Method != "mtls" || id.Attrs["serial"] != "7") {
+				t.Fatalf("got identity %+v", id)
+			}
+		})
+	}
+}
+
+func TestPasscodeName(t *testing.T) {
+	cases := []struct {
+		code Code
+		want string
+	}{
+		{"", "passcode"},
+		// the first 4 bytes of sha256("secret")
+		{"secret", "passcode:2bb80d53"},
+	}
+	for _, tc := range cases {
+		if got := passcodeName(tc.code); got != tc.want {
+			t.Errorf("passcodeName(%q) = %v, want %v", tc.code, got, tc.want)
+		}
+	}
+}
+
+// identityEngine sends the identity registers as the payload of a job
+type identityEngine struct{}
+
+func (identityEngine) Start(<-chan *Job) error { return nil }
+
+func (identityEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	return nil, status.Error(codes.Internal, "RegisterIdentity not called")
+}
+
+func (identityEngine) RegisterIdentity(id *Identity, sig chan Signal) (<-chan *Job, error) {
+	jobs := make(chan *Job, 1)
+	jobs <- CreateJob(id.Method+" "+id.Name, nil)
+	return jobs, nil
+}
+
+func TestServerAuth(t *testing.T) {
+	secret := []byte("secret")
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:   addr,
+		Engine: identityEngine{},
+		Auth:   &HMACAuthenticator{Secret: secret, MaxAge: time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	cases := []struct {
+		name   string
+		secret []byte
+		code   codes.Code
+	}{
+		{"signed", secret, codes.OK},
+		{"wrong secret", []byte("other"), codes.Unauthenticated},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := dialTest(t, &DialInfo{
+				Addr: addr,
+				Opts: []grpc.DialOption{
+					grpc.WithInsecure(),
+					grpc.WithPerRPCCredentials(HMACToken(tc.secret, "node")),
+				},
+			})
+			defer c.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			go func() {
+				<-ctx.Done()
+				c.Close()
+			}()
+			j, err := c.Ask()
+			if status.Code(err) != tc.code {
+				t.Fatalf("got error %v, want code %v", err, tc.code)
+			}
+			if err == nil && j.GetPayload() != "hmac node" {
+				t.Fatalf("got payload %v, want identity hmac node", j.GetPayload())
+			}
+		})
+	}
+}
@@ -2,32 +2,80 @@ package linkage
 
 import (
 	"context"
+	"errors"
 	"linkage/proto/job"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
 
 // DialInfo struct is the info for dial to remote service
+// MaxAttempt is the max times to build stream when reconnect,
+// it never gives up if MaxAttempt is less than 1.
+// Topics filter the jobs by routing key, see MatchTopic.
+// Window is the max number of jobs not acked yet the server can send,
+// no limit if Window is less than 1.
+// Compressions are accepted for jobs in preferred order, see CompressionGzip.
+// MaxJobSize is the max bytes of a compressed job after decompressed, default is 16MB
 type DialInfo struct {
-	ConnCode   Code
-	Addr       Addr
-	Opts       []grpc.DialOption
-	MaxAttempt int
+	ConnCode     Code
+	Addr         Addr
+	Opts         []grpc.DialOption
+	MaxAttempt   int
+	Topics       []string
+	Window       int
+	Compressions []string
+	MaxJobSize   int
 }
 
 // Client response for build the connection to remote linkage
 // and returns the job when user ask it
 type Client struct {
-	conn   *grpc.ClientConn
-	stream job.Service_AskClient
-	info   *DialInfo
+	mu          sync.Mutex
+	sendMu      sync.Mutex
+	ctx         context.Context
+	conn        *grpc.ClientConn
+	stream      job.Service_AskClient
+	info        *DialInfo
+	metrics     Metrics
+	backoff     func() Waiting
+	state       string
+	connectedAt time.Time
+	received    uint64
+	paused      bool
+	resume      chan struct{}
+	quit        <-chan struct{}
+}
+
+// States of the connection to upstream
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateClosed       = "closed"
+)
+
+var errNotConnected = errors.New("stream not connected")
+
+// maxBackoff is the longest wait between attempts to reconnect
+const maxBackoff = time.Minute
+
+// defaultBackoff waits a second at first and twice as long after each attempt up to maxBackoff
+func defaultBackoff() Waiting {
+	return Backoff(time.Second, maxBackoff)
 }
 
 // InitClient reutrn an Client instance
 func InitClient(info *DialInfo) (*Client, error) {
 	client := &Client{
-		info: info,
+		ctx:     context.Background(),
+		info:    info,
+		metrics: nopMetrics{},
+		backoff: defaultBackoff,
+		state:   StateConnecting,
 	}
 
 	return client, nil
@@ -35,7 +83,21 @@ func InitClient(info *DialInfo) (*Client, error) {
 
 // BuildStream to recieve jobs from remote lickage server
 func (s *Client) BuildStream() error {
-	err := s.dial()
+	return s.BuildStreamContext(context.Background())
+}
+
+// BuildStreamContext builds the stream ends when ctx is canceled,
+// Reconnect builds the stream with the same ctx
+func (s *Client) BuildStreamContext(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	return s.build(ctx)
+}
+
+func (s *Client) build(ctx context.Context) error {
+	conn, err := s.dial(ctx)
 	if err != nil {
 		log.Errorf("fail to dial, error: %v", err)
 		return err
@@ -45,60 +107,303 @@ func (s *Client) BuildStream() error {
 	}).Info("dial success")
 
 	// ask the stream for job
-	err = s.connect()
+	stream, err := s.connect(ctx, conn)
 	if err != nil {
 		log.Errorf("fail to connect, error: %v", err)
+		conn.Close()
 		return err
 	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.stream = stream
+	s.state = StateConnected
+	s.connectedAt = time.Now()
+	s.mu.Unlock()
 	log.Infof("connect success")
 	log.Infof("ready to recieve job")
 	return nil
 }
 
-func (s *Client) dial() error {
-	conn, err := grpc.Dial(s.info.Addr, s.info.Opts...)
+func (s *Client) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, s.info.Addr, s.info.Opts...)
+}
+
+func (s *Client) connect(ctx context.Context, conn *grpc.ClientConn) (job.Service_AskClient, error) {
+	client := job.NewServiceClient(conn)
+	stream, err := client.Ask(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.conn = conn
-	return nil
-}
-
-func (s *Client) connect() error {
-	client := job.NewServiceClient(s.conn)
-	stream, err := client.Ask(context.Background(), &job.Passphrase{
-		Code: s.info.ConnCode,
+	err = stream.Send(&job.Feedback{
+		Passphrase: &job.Passphrase{
+			Code:         s.info.ConnCode,
+			Topics:       s.info.Topics,
+			Compressions: s.info.Compressions,
+		},
+		Credit: s.credit(s.info.Window),
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	s.stream = stream
-	return nil
+	return stream, nil
+}
+
+func (s *Client) getStream() job.Service_AskClient {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream
 }
 
-// Ask recieve the job from server
+// Ask recieve the job from server,
+// the job can't be decompressed is nacked and the next one is recieved
 func (s *Client) Ask() (*Job, error) {
-	gj, err := s.stream.Recv()
-	if err != nil {
-		return nil, err
+	stream := s.getStream()
+	if stream == nil {
+		return nil, errNotConnected
+	}
+
+	for {
+		gj, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		err = decompressJob(gj, s.info.MaxJobSize)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":      gj.GetId(),
+				"address": s.info.Addr,
+			}).Errorf("decompress job fail, error: %v", err)
+			err = s.Nack(gj.GetId(), err.Error())
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		atomic.AddUint64(&s.received, 1)
+		return toLinkageJob(gj), nil
+	}
+}
+
+// Ack tells server the job is recieved, it is safe to call with Ask concurrently
+func (s *Client) Ack(id string) error {
+	return s.send(&job.Feedback{
+		Ack: &job.Ack{
+			Id: id,
+		},
+		Credit: s.credit(1),
+	})
+}
+
+// Nack tells server the job is not handled and should be redelivered
+func (s *Client) Nack(id string, reason string) error {
+	return s.send(&job.Feedback{
+		Ack: &job.Ack{
+			Id:     id,
+			Nack:   true,
+			Reason: reason,
+		},
+		Credit: s.credit(1),
+	})
+}
+
+// send sends feedback on the stream, one at a time
+func (s *Client) send(fb *job.Feedback) error {
+	stream := s.getStream()
+	if stream == nil {
+		return errNotConnected
 	}
 
-	return toLinkageJob(gj), nil
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return stream.Send(fb)
+}
+
+// credit returns n if flow control is enabled
+func (s *Client) credit(n int) uint32 {
+	if s.info.Window < 1 {
+		return 0
+	}
+	return uint32(n)
+}
+
+// errQuit is returned by Reconnect when linkage drains
+var errQuit = errors.New("client quit")
+
+// Reconnect closes the connection and builds the stream again until it succeeds
+// or the context of the client is canceled. Each call starts a new backoff of the client,
+// so the wait is reset once the stream is built.
+// It gives up after DialInfo.MaxAttempt attempts if it is set, or when the backoff returns an error.
+// The client of linkage stops reconnecting when linkage drains
+func (s *Client) Reconnect() error {
+	s.Close()
+	s.setState(StateReconnecting)
+
+	wait := s.backoff()
+
+	s.mu.Lock()
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	// the wait ends when the client quits, but the stream built lives with ctx
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.quit:
+			cancel()
+		case <-wctx.Done():
+		}
+	}()
+
+	for attempt := 1; ; attempt++ {
+		if s.quitting() {
+			s.setState(StateClosed)
+			return errQuit
+		}
+
+		s.metrics.ReconnectAttempt(s.info.Addr)
+		err := s.build(ctx)
+		if err == nil {
+			if s.quitting() {
+				// linkage started draining while building, it may have closed the client
+				s.Close()
+				return errQuit
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			s.setState(StateClosed)
+			return ctx.Err()
+		}
+
+		log.WithFields(log.Fields{
+			"address": s.info.Addr,
+			"attempt": attempt,
+		}).Errorf("fail to rebuild stream, error: %v", err)
+		if s.info.MaxAttempt > 0 && attempt >= s.info.MaxAttempt {
+			s.setState(StateClosed)
+			return err
+		}
+
+		start := time.Now()
+		werr := wait(wctx)
+		s.metrics.RetryWaited(time.Since(start))
+		if ctx.Err() != nil {
+			s.setState(StateClosed)
+			return ctx.Err()
+		}
+		if werr != nil && !s.quitting() {
+			s.setState(StateClosed)
+			return err
+		}
+	}
+}
+
+// quitting returns true if linkage of the client is draining
+func (s *Client) quitting() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
 }
 
 // Close closes the connection
 func (s *Client) Close() {
-	err := s.conn.Close()
-	if err != nil {
-		log.Errorf("conn close fail, error: %v", err)
-	} else {
-		log.Info("conn closed")
+	s.mu.Lock()
+	conn, stream := s.conn, s.stream
+	s.conn, s.stream = nil, nil
+	s.state = StateClosed
+	s.mu.Unlock()
+
+	if conn != nil {
+		err := conn.Close()
+		if err != nil {
+			log.Errorf("conn close fail, error: %v", err)
+		} else {
+			log.Info("conn closed")
+		}
 	}
-	err = s.stream.CloseSend()
-	if err != nil {
-		log.Errorf("stream close fail, error: %v", err)
-	} else {
-		log.Info("stream closed")
+
+	if stream != nil {
+		err := stream.CloseSend()
+		if err != nil {
+			log.Errorf("stream close fail, error: %v", err)
+		} else {
+			log.Info("stream closed")
+		}
+	}
+}
+
+func (s *Client) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+// State returns the state of the connection and the time it connected
+func (s *Client) State() (string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, s.connectedAt
+}
+
+// Received returns the number of jobs recieved
+func (s *Client) Received() uint64 {
+	return atomic.LoadUint64(&s.received)
+}
+
+// Pause stops linkage asking jobs by the client until Resume
+func (s *Client) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return
+	}
+	s.paused = true
+	s.resume = make(chan struct{})
+}
+
+// Resume lets linkage ask jobs by the client again
+func (s *Client) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.paused {
+		return
+	}
+	s.paused = false
+	close(s.resume)
+}
+
+// Paused returns true if the client is paused
+func (s *Client) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// waitResumed blocks while the client is paused,
+// it returns false if quit is closed before resumed
+func (s *Client) waitResumed(quit <-chan struct{}) bool {
+	s.mu.Lock()
+	paused, resume := s.paused, s.resume
+	s.mu.Unlock()
+
+	if !paused {
+		return true
+	}
+
+	select {
+	case <-resume:
+		return true
+	case <-quit:
+		return false
 	}
 }
@@ -0,0 +1,62 @@
+// Command linkage-replay serves the jobs in a dead letter file to linkages
+// dialing it as an upstream, it exits when all of them are acked
+package main
+
+import (
+	"flag"
+	"linkage"
+	"linkage/engines"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	path := flag.String("file", "deadletters.log", "path of the dead letter file")
+	addr := flag.String("listen", ":8082", "address to serve the jobs")
+	code := flag.String("code", "", "passcode of downstreams, any passcode is accepted if it is empty")
+	reason := flag.String("reason", "", "only replay dead letters of the reason if it is set")
+	keepDeadline := flag.Bool("keep-deadline", false, "keep deadlines of the jobs, the expired ones are dropped instead of replayed")
+	timeout := flag.Duration("timeout", 0, "give up the jobs not acked after timeout, 0 means never")
+	flag.Parse()
+
+	letters, err := linkage.ReadDeadLetters(*path)
+	if err != nil {
+		log.Errorf("read dead letters fail, error: %v", err)
+		os.Exit(1)
+	}
+
+	var jobs []*linkage.Job
+	for _, dl := range letters {
+		if *reason != "" && dl.Reason != *reason {
+			continue
+		}
+		if dl.Job.GetID() == "" {
+			log.Warn("skip dead letter without job id")
+			continue
+		}
+		// dead letters are often past their deadlines
+		if !*keepDeadline {
+			dl.Job.Deadline = time.Time{}
+		}
+		jobs = append(jobs, dl.Job)
+	}
+	if len(jobs) == 0 {
+		log.Info("no dead letter to replay")
+		return
+	}
+
+	e := engines.InitFeedEngine(jobs)
+	err = e.Serve(&linkage.ServerConfig{
+		Addr: *addr,
+		CodeAssert: func(c linkage.Code) bool {
+			return *code == "" || c == *code
+		},
+	}, *timeout)
+	if err != nil {
+		log.Errorf("replay fail, error: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("%v jobs replayed", len(jobs))
+}
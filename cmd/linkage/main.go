@@ -0,0 +1,49 @@
+// Command linkage runs a linkage node described by a config file,
+// it drains the node and exits on SIGTERM or SIGINT
+package main
+
+import (
+	"context"
+	"flag"
+	"linkage/config"
+	"os"
+	"os/signal"
+	"syscall"
+
+	// register built-in engines
+	_ "linkage/engines"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	path := flag.String("config", "linkage.yaml", "path of the yaml or json config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		log.Errorf("load config fail, error: %v", err)
+		os.Exit(1)
+	}
+
+	l, err := config.Build(cfg)
+	if err != nil {
+		log.Errorf("build linkage fail, error: %v", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigs
+		log.Infof("receive %v, drain linkage", sig)
+		cancel()
+	}()
+
+	err = l.RunContext(ctx)
+	if err != nil && err != context.Canceled {
+		log.Errorf("linkage stopped, error: %v", err)
+		os.Exit(1)
+	}
+}
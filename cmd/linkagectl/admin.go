@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"linkage"
+	"linkage/proto/admin"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// adminFlags is the flags of admin commands
+type adminFlags struct {
+	conn
+	code string
+	raw  bool
+}
+
+func (a *adminFlags) parse(name string, args []string, more func(fs *flag.FlagSet)) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	a.flags(fs)
+	fs.StringVar(&a.code, "admin-code", "", "admin passcode of the node")
+	fs.BoolVar(&a.raw, "json", false, "print the response as json")
+	if more != nil {
+		more(fs)
+	}
+	fs.Parse(args)
+}
+
+// client returns the admin client and the context carries admin passcode
+func (a *adminFlags) client() (admin.AdminClient, context.Context, func(), error) {
+	cc, err := a.dial()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if a.code != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, linkage.MetaAdminCode, a.code)
+	}
+	return admin.NewAdminClient(cc), ctx, func() {
+		cancel()
+		cc.Close()
+	}, nil
+}
+
+func upstreams(args []string) error {
+	var a adminFlags
+	a.parse("upstreams", args, nil)
+
+	ac, ctx, done, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	resp, err := ac.ListUpstreams(ctx, &admin.ListUpstreamsRequest{})
+	if err != nil {
+		return err
+	}
+	if a.raw {
+		return printJSON(resp)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDR\tSTATE\tPAUSED\tTOPICS\tRECEIVED\tCONNECTED")
+	for _, u := range resp.GetUpstreams() {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			u.GetAddr(), u.GetState(), u.GetPaused(), strings.Join(u.GetTopics(), ","),
+			u.GetJobsReceived(), since(u.GetConnectedAt()))
+	}
+	w.Flush()
+	fmt.Printf("\njobs waiting for engine: %v\n", resp.GetIncomeQueued())
+	return nil
+}
+
+func downstreams(args []string) error {
+	var a adminFlags
+	a.parse("downstreams", args, nil)
+
+	ac, ctx, done, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	resp, err := ac.ListDownstreams(ctx, &admin.ListDownstreamsRequest{})
+	if err != nil {
+		return err
+	}
+	if a.raw {
+		return printJSON(resp)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPEER\tIDENTITY\tTOPICS\tSENT\tUNACKED\tQUEUED\tCREDIT\tCOMPRESSION\tCONNECTED")
+	for _, d := range resp.GetDownstreams() {
+		credit := fmt.Sprint(d.GetCredit())
+		if d.GetCredit() < 0 {
+			credit = "-"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v (%v)\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			d.GetId(), d.GetPeer(), d.GetIdentity(), d.GetAuthMethod(), strings.Join(d.GetTopics(), ","),
+			d.GetJobsSent(), d.GetUnacked(), d.GetQueued(), credit, d.GetCompression(), since(d.GetConnectedAt()))
+	}
+	w.Flush()
+	fmt.Printf("\njobs no downstream subscribes: %v\n", resp.GetUnrouted())
+	return nil
+}
+
+func disconnect(args []string) error {
+	var a adminFlags
+	var id, reason string
+	a.parse("disconnect", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&id, "id", "", "id of the downstream, see downstreams")
+		fs.StringVar(&reason, "reason", "disconnected by linkagectl", "reason sent to the downstream")
+	})
+	if id == "" {
+		return errors.New("-id is required")
+	}
+
+	ac, ctx, done, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	_, err = ac.Disconnect(ctx, &admin.DisconnectRequest{
+		Id:     id,
+		Reason: reason,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("downstream %v disconnected\n", id)
+	return nil
+}
+
+func pause(args []string) error {
+	var a adminFlags
+	var upstream string
+	a.parse("pause", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&upstream, "upstream", "", "address of the upstream, all upstreams if it is empty")
+	})
+
+	ac, ctx, done, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	resp, err := ac.PauseUpstream(ctx, &admin.PauseUpstreamRequest{
+		Addr: upstream,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("paused: %v\n", strings.Join(resp.GetPaused(), ", "))
+	return nil
+}
+
+func resume(args []string) error {
+	var a adminFlags
+	var upstream string
+	a.parse("resume", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&upstream, "upstream", "", "address of the upstream, all upstreams if it is empty")
+	})
+
+	ac, ctx, done, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	resp, err := ac.ResumeUpstream(ctx, &admin.ResumeUpstreamRequest{
+		Addr: upstream,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("resumed: %v\n", strings.Join(resp.GetResumed(), ", "))
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// since returns how long ago of unix nano, "-" if it is 0
+func since(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return time.Since(time.Unix(0, n)).Round(time.Second).String() + " ago"
+}
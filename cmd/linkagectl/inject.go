@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"linkage"
+	"linkage/engines"
+	"os"
+	"strings"
+	"time"
+)
+
+// inject submits the jobs to the node, or serves them to nodes dialing it as upstream
+// until all are acked or expired if -listen is set.
+// The payload of each job is an argument, or stdin if there is no argument
+func inject(args []string) error {
+	var c conn
+	var meta list
+	fs := flag.NewFlagSet("inject", flag.ExitOnError)
+	c.flags(fs)
+	listen := fs.String("listen", "", "address to serve the jobs instead of submitting them to -addr")
+	code := fs.String("code", "", "passcode to submit, or passcode of downstreams when serving, any passcode is accepted if it is empty")
+	routingKey := fs.String("routing-key", "", "routing key of the jobs")
+	priority := fs.Int("priority", 0, "priority of the jobs")
+	fs.Var(&meta, "meta", "metadata key=value, can be set multiple times")
+	contentType := fs.String("content-type", "", "content type of the payload, payload is binary unless it is empty, json or text")
+	ttl := fs.Duration("ttl", 0, "jobs expire after ttl, 0 means never")
+	timeout := fs.Duration("timeout", 0, "give up serving the jobs not acked after timeout, 0 means never")
+	lines := fs.Bool("lines", false, "each line of stdin is a job")
+	fs.Parse(args)
+
+	payloads, err := readPayloads(fs.Args(), *lines)
+	if err != nil {
+		return err
+	}
+	if len(payloads) == 0 {
+		return errors.New("no job to inject")
+	}
+
+	md, err := parseMeta(meta)
+	if err != nil {
+		return err
+	}
+
+	jobs := make([]*linkage.Job, 0, len(payloads))
+	for _, p := range payloads {
+		cp := make(map[string]string, len(md))
+		for k, v := range md {
+			cp[k] = v
+		}
+		j := engines.NewJob([]byte(p), *contentType, "", cp)
+		j.RoutingKey = *routingKey
+		j.Priority = int32(*priority)
+		if *ttl > 0 {
+			j.Deadline = j.CreatedAt.Add(*ttl)
+		}
+		jobs = append(jobs, j)
+	}
+
+	if *listen != "" {
+		return serve(*listen, *code, jobs, *timeout)
+	}
+	return submit(&c, *code, jobs)
+}
+
+// submit pushes the jobs to the node and prints the receipts
+func submit(c *conn, code string, jobs []*linkage.Job) error {
+	opts, err := c.dialOptions()
+	if err != nil {
+		return err
+	}
+
+	cli, err := linkage.InitClient(&linkage.DialInfo{
+		ConnCode: code,
+		Addr:     c.addr,
+		Opts:     opts,
+	})
+	if err != nil {
+		return err
+	}
+
+	receipts, err := cli.Submit(jobs...)
+	if err != nil {
+		return err
+	}
+
+	rejected := 0
+	for _, r := range receipts {
+		if r.Accepted {
+			fmt.Printf("accepted %v\n", r.ID)
+			continue
+		}
+		rejected++
+		fmt.Printf("rejected %v: %v\n", r.ID, r.Reason)
+	}
+	if rejected > 0 {
+		return fmt.Errorf("%v of %v jobs rejected", rejected, len(receipts))
+	}
+	return nil
+}
+
+func readPayloads(args []string, lines bool) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+
+	if !lines {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []string{string(b)}, nil
+	}
+
+	var payloads []string
+	sc := bufio.NewScanner(os.Stdin)
+	sc.Buffer(nil, 16*1024*1024)
+	for sc.Scan() {
+		if sc.Text() != "" {
+			payloads = append(payloads, sc.Text())
+		}
+	}
+	return payloads, sc.Err()
+}
+
+func parseMeta(meta list) (map[string]string, error) {
+	md := make(map[string]string, len(meta))
+	for _, kv := range meta {
+		i := strings.Index(kv, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("metadata %q should be key=value", kv)
+		}
+		md[kv[:i]] = kv[i+1:]
+	}
+	return md, nil
+}
+
+// serve serves the jobs until all are acked or expired
+func serve(listen, code string, jobs []*linkage.Job, timeout time.Duration) error {
+	e := engines.InitFeedEngine(jobs)
+	err := e.Serve(&linkage.ServerConfig{
+		Addr: listen,
+		CodeAssert: func(c linkage.Code) bool {
+			return code == "" || c == code
+		},
+	}, timeout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%v jobs acked or expired\n", len(jobs))
+	return nil
+}
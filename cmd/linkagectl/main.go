@@ -0,0 +1,147 @@
+// Command linkagectl inspects and drives linkage nodes:
+//
+//	linkagectl tail -consume -addr host:port        prints and consumes jobs from the Ask stream of a node
+//	linkagectl inject -addr host:port payload...    submits jobs to a node, or serves them with -listen
+//	linkagectl upstreams -addr host:port            lists upstreams of a node with admin service
+//	linkagectl downstreams -addr host:port          lists downstream streams of a node
+//	linkagectl disconnect -addr host:port -id id    disconnects a downstream
+//	linkagectl pause|resume -addr host:port         pauses or resumes asking jobs from upstreams
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"linkage"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"tail":        {"print and consume jobs from the Ask stream of a node", tail},
+	"inject":      {"submit jobs to a node or serve them as upstream", inject},
+	"upstreams":   {"list upstreams of a node", upstreams},
+	"downstreams": {"list downstream streams of a node", downstreams},
+	"disconnect":  {"disconnect a downstream of a node", disconnect},
+	"pause":       {"pause asking jobs from upstreams of a node", pause},
+	"resume":      {"resume asking jobs from upstreams of a node", resume},
+}
+
+func main() {
+	log.SetLevel(log.WarnLevel)
+	log.SetOutput(os.Stderr)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	err := cmd.run(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: linkagectl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range []string{"tail", "inject", "upstreams", "downstreams", "disconnect", "pause", "resume"} {
+		fmt.Fprintf(os.Stderr, "  %-12s %v\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "run linkagectl <command> -h for flags")
+}
+
+// conn is the flags to connect to a node
+type conn struct {
+	addr       string
+	ca         string
+	cert       string
+	key        string
+	serverName string
+	apiKey     string
+}
+
+func (c *conn) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", "localhost:8081", "address of the node")
+	fs.StringVar(&c.ca, "ca", "", "CA certificate to verify the node, connect with TLS if it is set")
+	fs.StringVar(&c.cert, "cert", "", "client certificate for mtls")
+	fs.StringVar(&c.key, "key", "", "client key for mtls")
+	fs.StringVar(&c.serverName, "server-name", "", "server name to verify the node")
+	fs.StringVar(&c.apiKey, "api-key", "", "api key sent as credentials")
+}
+
+func (c *conn) dialOptions() ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	if c.ca == "" {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		b, err := ioutil.ReadFile(c.ca)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificate in %v", c.ca)
+		}
+
+		tc := &tls.Config{
+			RootCAs:    pool,
+			ServerName: c.serverName,
+		}
+		if c.cert != "" {
+			cert, err := tls.LoadX509KeyPair(c.cert, c.key)
+			if err != nil {
+				return nil, err
+			}
+			tc.Certificates = []tls.Certificate{cert}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tc)))
+	}
+
+	if c.apiKey != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(linkage.APIKey(c.apiKey)))
+	}
+	return opts, nil
+}
+
+func (c *conn) dial() (*grpc.ClientConn, error) {
+	opts, err := c.dialOptions()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(c.addr, opts...)
+}
+
+// list is a flag can be set multiple times or separated by comma
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"linkage"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errNotConsume refuses to tail without -consume
+var errNotConsume = errors.New("tail acks the jobs it prints so other downstreams never get them, set -consume to run it")
+
+// tail prints the jobs from the Ask stream of a node,
+// the jobs are acked so they are taken from other downstreams, it runs only if -consume is set
+func tail(args []string) error {
+	var c conn
+	var topics list
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	c.flags(fs)
+	code := fs.String("code", "", "passcode of the node")
+	fs.Var(&topics, "topic", "topic to subscribe, can be set multiple times")
+	n := fs.Int("n", 0, "exit after n jobs, 0 means never")
+	consume := fs.Bool("consume", false, "consume the jobs, they are acked and not sent to other downstreams")
+	raw := fs.Bool("json", false, "print jobs as json lines")
+	fs.Parse(args)
+
+	if !*consume {
+		return errNotConsume
+	}
+
+	opts, err := c.dialOptions()
+	if err != nil {
+		return err
+	}
+
+	cli, err := linkage.InitClient(&linkage.DialInfo{
+		ConnCode:     *code,
+		Addr:         c.addr,
+		Opts:         opts,
+		Topics:       topics,
+		Window:       1,
+		Compressions: []string{linkage.CompressionZstd, linkage.CompressionSnappy, linkage.CompressionGzip},
+	})
+	if err != nil {
+		return err
+	}
+	err = cli.BuildStream()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for i := 0; *n == 0 || i < *n; i++ {
+		j, err := cli.Ask()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if *raw {
+			err = json.NewEncoder(os.Stdout).Encode(j)
+		} else {
+			err = printJob(os.Stdout, j)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = cli.Ack(j.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxBinary is the max bytes of binary payload printed
+const maxBinary = 256
+
+func printJob(w io.Writer, j *linkage.Job) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- job %v\n", j.ID)
+	if j.RoutingKey != "" {
+		fmt.Fprintf(&b, "routing key: %v\n", j.RoutingKey)
+	}
+	if j.Priority != 0 {
+		fmt.Fprintf(&b, "priority:    %v\n", j.Priority)
+	}
+	if !j.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "created at:  %v\n", j.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if !j.Deadline.IsZero() {
+		fmt.Fprintf(&b, "deadline:    %v\n", j.Deadline.Format(time.RFC3339Nano))
+	}
+	if j.Trace.IsValid() {
+		fmt.Fprintf(&b, "trace:       %v\n", j.Trace)
+	}
+	if j.ContentType != "" || j.Encoding != "" {
+		fmt.Fprintf(&b, "content:     %v\n", strings.TrimSpace(j.ContentType+" "+j.Encoding))
+	}
+
+	if len(j.Metadata) > 0 {
+		keys := make([]string, 0, len(j.Metadata))
+		for k := range j.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("metadata:\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %v: %v\n", k, j.Metadata[k])
+		}
+	}
+
+	b.WriteString("payload:\n")
+	b.WriteString(formatPayload(j))
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatPayload indents json, prints text as it is and dumps binary in hex
+func formatPayload(j *linkage.Job) string {
+	p, err := j.PayloadBytes()
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+
+	var out bytes.Buffer
+	if json.Indent(&out, p, "  ", "  ") == nil {
+		return "  " + out.String()
+	}
+
+	if j.Data == nil || strings.HasPrefix(j.ContentType, "text/") {
+		return "  " + string(p)
+	}
+
+	if len(p) > maxBinary {
+		return fmt.Sprintf("%v... (%v bytes)", hex.Dump(p[:maxBinary]), len(p))
+	}
+	return hex.Dump(p)
+}
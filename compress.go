@@ -0,0 +1,243 @@
+package linkage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"linkage/proto/job"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions of jobs on Ask stream
+const (
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// defaultMinCompressSize is the MinCompressSize if it is not set
+const defaultMinCompressSize = 1024
+
+// defaultMaxJobSize is the MaxJobSize if it is not set
+const defaultMaxJobSize = 16 << 20
+
+var errJobTooLarge = errors.New("decompressed job too large")
+
+// compressor decompresses at most max bytes, errJobTooLarge is returned for more
+type compressor interface {
+	compress(b []byte) ([]byte, error)
+	decompress(b []byte, max int) ([]byte, error)
+}
+
+var compressors = map[string]compressor{
+	CompressionGzip:   gzipCompressor{},
+	CompressionSnappy: snappyCompressor{},
+	CompressionZstd:   &zstdCompressor{},
+}
+
+// negotiate returns the first compression offered by downstream the server allows,
+// it is empty if there is none
+func negotiate(offered []string, allowed []string) string {
+	for _, o := range offered {
+		if _, ok := compressors[o]; !ok {
+			continue
+		}
+
+		for _, a := range allowed {
+			if o == a {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+// compressJob compresses data and payload of the job if they are not smaller than min
+func compressJob(gj *job.Job, name string, min int) error {
+	c, ok := compressors[name]
+	if !ok {
+		return fmt.Errorf("unknown compression %v", name)
+	}
+	if len(gj.Data)+len(gj.Payload) < min {
+		return nil
+	}
+
+	data, err := compressBytes(c, gj.Data)
+	if err != nil {
+		return err
+	}
+	payload, err := compressBytes(c, []byte(gj.Payload))
+	if err != nil {
+		return err
+	}
+
+	gj.Data = data
+	gj.CompressedPayload = payload
+	gj.Payload = ""
+	gj.Compression = name
+	return nil
+}
+
+// decompressJob restores data and payload of the job compressed by compressJob,
+// it fails if they are more than max bytes together, defaultMaxJobSize if max is less than 1
+func decompressJob(gj *job.Job, max int) error {
+	if gj.Compression == "" {
+		return nil
+	}
+
+	c, ok := compressors[gj.Compression]
+	if !ok {
+		return fmt.Errorf("unknown compression %v", gj.Compression)
+	}
+	if max < 1 {
+		max = defaultMaxJobSize
+	}
+
+	data, err := decompressBytes(c, gj.Data, max)
+	if err != nil {
+		return err
+	}
+	payload, err := decompressBytes(c, gj.CompressedPayload, max)
+	if err != nil {
+		return err
+	}
+	if len(data)+len(payload) > max {
+		return errJobTooLarge
+	}
+
+	gj.Data = data
+	gj.Payload = string(payload)
+	gj.CompressedPayload = nil
+	gj.Compression = ""
+	return nil
+}
+
+// compressBytes keeps empty bytes empty
+func compressBytes(c compressor, b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return b, nil
+	}
+	return c.compress(b)
+}
+
+func decompressBytes(c compressor, b []byte, max int) ([]byte, error) {
+	if len(b) == 0 {
+		return b, nil
+	}
+	return c.decompress(b, max)
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(b)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) decompress(b []byte, max int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// read one byte more to know if it is over max
+	out, err := ioutil.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > max {
+		return nil, errJobTooLarge
+	}
+	return out, nil
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) compress(b []byte) ([]byte, error) {
+	return snappy.Encode(nil, b), nil
+}
+
+func (snappyCompressor) decompress(b []byte, max int) ([]byte, error) {
+	n, err := snappy.DecodedLen(b)
+	if err != nil {
+		return nil, err
+	}
+	if n > max {
+		return nil, errJobTooLarge
+	}
+	return snappy.Decode(nil, b)
+}
+
+// zstdCompressor shares the encoder, and the decoder of each max size,
+// they are created when first used
+type zstdCompressor struct {
+	once sync.Once
+	enc  *zstd.Encoder
+	err  error
+	mu   sync.Mutex
+	decs map[int]*zstd.Decoder
+}
+
+func (z *zstdCompressor) init() error {
+	z.once.Do(func() {
+		z.enc, z.err = zstd.NewWriter(nil)
+	})
+	return z.err
+}
+
+// decoder returns the decoder decodes at most max bytes
+func (z *zstdCompressor) decoder(max int) (*zstd.Decoder, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if dec, ok := z.decs[max]; ok {
+		return dec, nil
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(max)))
+	if err != nil {
+		return nil, err
+	}
+	if z.decs == nil {
+		z.decs = make(map[int]*zstd.Decoder)
+	}
+	z.decs[max] = dec
+	return dec, nil
+}
+
+func (z *zstdCompressor) compress(b []byte) ([]byte, error) {
+	err := z.init()
+	if err != nil {
+		return nil, err
+	}
+	return z.enc.EncodeAll(b, nil), nil
+}
+
+func (z *zstdCompressor) decompress(b []byte, max int) ([]byte, error) {
+	dec, err := z.decoder(max)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := dec.DecodeAll(b, nil)
+	if err == zstd.ErrDecoderSizeExceeded {
+		return nil, errJobTooLarge
+	}
+	return out, err
+}
@@ -0,0 +1,169 @@
+package linkage
+
+import (
+	"bytes"
+	"linkage/proto/job"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestCompressJob(t *testing.T) {
+	payload := strings.Repeat(`{"a":1}`, 300)
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 600)
+
+	cases := []struct {
+		name       string
+		payload    string
+		data       []byte
+		min        int
+		compressed bool
+	}{
+		{CompressionGzip, payload, nil, 1024, true},
+		{CompressionGzip, "", data, 1024, true},
+		{CompressionSnappy, payload, data, 1024, true},
+		{CompressionZstd, payload, nil, 1024, true},
+		{CompressionZstd, "", data, 1024, true},
+		{CompressionZstd, "small", nil, 1024, false},
+	}
+
+	for _, c := range cases {
+		gj := &job.Job{
+			Id:      "1",
+			Payload: c.payload,
+			Data:    append([]byte(nil), c.data...),
+		}
+		err := compressJob(gj, c.name, c.min)
+		if err != nil {
+			t.Fatalf("%v: compress fail, error: %v", c.name, err)
+		}
+		if (gj.Compression != "") != c.compressed {
+			t.Errorf("%v: compression = %q, want compressed %v", c.name, gj.Compression, c.compressed)
+		}
+		if c.compressed && (gj.Payload != "" || len(gj.Data)+len(gj.CompressedPayload) >= len(c.data)+len(c.payload)) {
+			t.Errorf("%v: job is not compressed", c.name)
+		}
+
+		err = decompressJob(gj, 0)
+		if err != nil {
+			t.Fatalf("%v: decompress fail, error: %v", c.name, err)
+		}
+		if gj.Payload != c.payload || !bytes.Equal(gj.Data, c.data) || gj.Compression != "" {
+			t.Errorf("%v: job is not restored", c.name)
+		}
+	}
+}
+
+func TestCompressUnknown(t *testing.T) {
+	err := compressJob(&job.Job{Payload: "x"}, "lz4", 0)
+	if err == nil {
+		t.Error("compress by unknown compression")
+	}
+
+	err = decompressJob(&job.Job{Compression: "lz4", CompressedPayload: []byte("x")}, 0)
+	if err == nil {
+		t.Error("decompress by unknown compression")
+	}
+}
+
+func TestDecompressMax(t *testing.T) {
+	payload := strings.Repeat("a", 2048)
+	cases := []struct {
+		name string
+		data []byte
+		max  int
+		ok   bool
+	}{
+		{CompressionGzip, nil, 2048, true},
+		{CompressionGzip, nil, 2047, false},
+		{CompressionSnappy, nil, 2048, true},
+		{CompressionSnappy, nil, 1024, false},
+		{CompressionZstd, nil, 2048, true},
+		{CompressionZstd, nil, 1024, false},
+		{CompressionZstd, bytes.Repeat([]byte{1}, 2048), 3072, false},
+		{CompressionZstd, nil, 0, true},
+	}
+
+	for _, c := range cases {
+		gj := &job.Job{
+			Payload: payload,
+			Data:    c.data,
+		}
+		err := compressJob(gj, c.name, 0)
+		if err != nil {
+			t.Fatalf("%v: compress fail, error: %v", c.name, err)
+		}
+
+		err = decompressJob(gj, c.max)
+		if c.ok != (err == nil) {
+			t.Errorf("%v: decompress %v bytes by max %v, error: %v", c.name, len(payload)+len(c.data), c.max, err)
+		}
+		if !c.ok && err != errJobTooLarge {
+			t.Errorf("%v: got error %v, want %v", c.name, err, errJobTooLarge)
+		}
+	}
+}
+
+func TestClientMaxJobSize(t *testing.T) {
+	big := CreateJob(strings.Repeat("a", 4096), nil)
+	small := CreateJob("small", nil)
+	sink := InitMemoryDeadLetters(0)
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:         addr,
+		Engine:       &jobsEngine{jobs: []*Job{big, small}, sigs: make(chan Signal, 16)},
+		CodeAssert:   func(Code) bool { return true },
+		DeadLetter:   sink,
+		MaxAttempts:  1,
+		Compressions: []string{CompressionZstd},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	c := dialTest(t, &DialInfo{
+		Addr:         addr,
+		Opts:         []grpc.DialOption{grpc.WithInsecure()},
+		Compressions: []string{CompressionZstd},
+		MaxJobSize:   2048,
+	})
+	defer c.Close()
+
+	// the big job is nacked by client and given up by server
+	j := askTimeout(t, c, time.Second)
+	if j == nil || j.Payload != "small" {
+		t.Fatalf("got job %+v, want small", j)
+	}
+	var letters []*DeadLetter
+	for i := 0; i < 50 && len(letters) == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+		letters = sink.Letters()
+	}
+	if len(letters) != 1 || letters[0].Reason != "nacked" || letters[0].LastError != errJobTooLarge.Error() {
+		t.Fatalf("got letters %+v, want the big job nacked", letters)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	allowed := []string{CompressionGzip, CompressionZstd}
+	cases := []struct {
+		offered []string
+		want    string
+	}{
+		{nil, ""},
+		{[]string{CompressionSnappy}, ""},
+		{[]string{"lz4", CompressionZstd, CompressionGzip}, CompressionZstd},
+		{[]string{CompressionGzip, CompressionZstd}, CompressionGzip},
+	}
+
+	for _, c := range cases {
+		got := negotiate(c.offered, allowed)
+		if got != c.want {
+			t.Errorf("negotiate(%v) = %q, want %q", c.offered, got, c.want)
+		}
+	}
+}
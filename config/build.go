@@ -0,0 +1,340 @@
+package config
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"linkage"
+	"linkage/metrics"
+	"linkage/trace"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Build creates the linkage node described by the config
+func Build(cfg *Config) (*linkage.Linkage, error) {
+	engine, err := linkage.NewEngine(cfg.Engine.Name, cfg.Engine.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var srvOpts []grpc.ServerOption
+	if cfg.TLS != nil {
+		creds, err := serverCredentials(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		srvOpts = append(srvOpts, grpc.Creds(creds))
+	}
+
+	var dis []*linkage.DialInfo
+	for _, up := range cfg.Upstreams {
+		di, err := dialInfo(up)
+		if err != nil {
+			return nil, err
+		}
+		di.MaxJobSize = cfg.MaxJobSize
+		dis = append(dis, di)
+	}
+
+	codeAssert, auth, err := authenticate(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []linkage.Option
+	if auth != nil {
+		opts = append(opts, linkage.WithAuthenticator(auth))
+	}
+
+	if cfg.Waiting != nil {
+		// each upstream waits by its own waiting function
+		wt := cfg.Waiting.withDefaults()
+		opts = append(opts, linkage.WithBackoff(func() linkage.Waiting {
+			return linkage.WaitFactory(wt.Init, wt.Grow, wt.MaxRetry)
+		}))
+	}
+
+	if cfg.Store != "" {
+		store, err := linkage.OpenFileStore(cfg.Store)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, linkage.WithJobStore(store))
+	}
+
+	if cfg.DeadLetter != nil {
+		// forwarded dead letters are served with the credentials of the node
+		sink, err := deadLetter(cfg.DeadLetter, &linkage.ServerConfig{
+			SrvOpts:    srvOpts,
+			CodeAssert: codeAssert,
+			Auth:       auth,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, linkage.WithDeadLetter(sink, cfg.DeadLetter.MaxAttempts))
+	}
+
+	if len(cfg.Compressions) > 0 {
+		opts = append(opts, linkage.WithCompressions(cfg.MinCompressSize, cfg.Compressions...))
+	}
+	if cfg.MaxJobSize > 0 {
+		opts = append(opts, linkage.WithMaxJobSize(cfg.MaxJobSize))
+	}
+	if cfg.MaxSubmitJobs > 0 {
+		opts = append(opts, linkage.WithMaxSubmitJobs(cfg.MaxSubmitJobs))
+	}
+
+	if cfg.Tracing != nil {
+		e, err := exporter(cfg.Tracing)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, linkage.WithSpanExporter(e))
+	}
+
+	if cfg.Admin != nil {
+		var codeAssert linkage.CodeAssert
+		if len(cfg.Admin.Passcodes) > 0 {
+			codeAssert = passcodeAssert(cfg.Admin.Passcodes)
+		} else {
+			log.Warn("no admin passcode, admin requests are denied")
+		}
+		opts = append(opts, linkage.WithAdmin(codeAssert))
+	}
+
+	if cfg.Gateway != nil {
+		var tc *tls.Config
+		if cfg.TLS != nil {
+			tc, err = serverTLS(cfg.TLS)
+			if err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, linkage.WithHTTPGateway(cfg.Gateway.Listen, tc))
+		if len(cfg.Gateway.Origins) > 0 {
+			opts = append(opts, linkage.WithGatewayOrigins(cfg.Gateway.Origins...))
+		}
+	}
+
+	if cfg.Drain != nil {
+		opts = append(opts, linkage.WithDrainTimeouts(linkage.DrainTimeouts{
+			Upstream:   time.Duration(cfg.Drain.Upstream),
+			Engine:     time.Duration(cfg.Drain.Engine),
+			Downstream: time.Duration(cfg.Drain.Downstream),
+			Shutdown:   time.Duration(cfg.Drain.Shutdown),
+		}))
+	}
+
+	if cfg.Metrics != nil {
+		m := metrics.InitPrometheus("linkage")
+		path := cfg.Metrics.Path
+		if path == "" {
+			path = "/metrics"
+		}
+		go func() {
+			err := m.Serve(cfg.Metrics.Listen, path)
+			log.Errorf("metrics server stopped, error: %v", err)
+		}()
+		opts = append(opts, linkage.WithMetrics(m))
+	}
+
+	return linkage.InitLinkage(cfg.Listen, engine, srvOpts, codeAssert, dis, nil, opts...)
+}
+
+// deadLetter returns nil if no sink is set,
+// the forward sink is served by srvCfg at the Forward address and spills to File
+func deadLetter(dl *DeadLetter, srvCfg *linkage.ServerConfig) (linkage.DeadLetterSink, error) {
+	var file *linkage.FileDeadLetters
+	if dl.File != "" {
+		f, err := linkage.OpenFileDeadLetters(dl.File)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+	}
+
+	switch {
+	case dl.Forward != "":
+		srvCfg.Addr = dl.Forward
+		f, err := linkage.InitForwardDeadLettersConfig(srvCfg)
+		if err != nil {
+			if file != nil {
+				file.Close()
+			}
+			return nil, err
+		}
+		f.MaxQueued = dl.MaxQueued
+		if file != nil {
+			f.Spill = file
+		}
+		return f, nil
+	case file != nil:
+		return file, nil
+	default:
+		return nil, nil
+	}
+}
+
+func exporter(t *Tracing) (linkage.SpanExporter, error) {
+	switch t.Exporter {
+	case "stdout":
+		return trace.InitStdout(nil), nil
+	case "otlp":
+		service := t.Service
+		if service == "" {
+			service = "linkage"
+		}
+		return trace.InitOTLP(t.Endpoint, service), nil
+	default:
+		return nil, fmt.Errorf("unknown span exporter %v", t.Exporter)
+	}
+}
+
+// authenticate returns the code assert for passcode, otherwise the authenticator
+func authenticate(auth *Auth) (linkage.CodeAssert, linkage.Authenticator, error) {
+	if auth == nil {
+		auth = &Auth{}
+	}
+
+	var a linkage.Authenticator
+	switch auth.Type {
+	case "", "passcode":
+		return passcodeAssert(auth.Passcodes), nil, nil
+	case "mtls":
+		a = &linkage.TLSAuthenticator{
+			Names: auth.Names,
+		}
+	case "hmac":
+		a = &linkage.HMACAuthenticator{
+			Secret: []byte(auth.Secret),
+			MaxAge: time.Duration(auth.MaxAge),
+		}
+	case "apikey":
+		a = &linkage.APIKeyAuthenticator{
+			Keys: auth.Keys,
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown auth type %v", auth.Type)
+	}
+
+	return nil, a, nil
+}
+
+// passcodeAssert accepts any passcode if passcodes is empty
+func passcodeAssert(passcodes []string) linkage.CodeAssert {
+	return func(code linkage.Code) bool {
+		if len(passcodes) == 0 {
+			return true
+		}
+
+		// compare all passcodes in constant time, so the time taken tells nothing
+		ok := false
+		for _, p := range passcodes {
+			if subtle.ConstantTimeCompare([]byte(p), []byte(code)) == 1 {
+				ok = true
+			}
+		}
+		return ok
+	}
+}
+
+func dialInfo(up Upstream) (*linkage.DialInfo, error) {
+	var opts []grpc.DialOption
+	if up.TLS == nil {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		creds, err := clientCredentials(up.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	}
+
+	if up.APIKey != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(linkage.APIKey(up.APIKey)))
+	}
+	if up.HMAC != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(linkage.HMACToken([]byte(up.HMAC.Secret), up.HMAC.Name)))
+	}
+
+	return &linkage.DialInfo{
+		ConnCode:     up.Code,
+		Addr:         up.Addr,
+		Opts:         opts,
+		MaxAttempt:   up.MaxAttempt,
+		Topics:       up.Topics,
+		Window:       up.Window,
+		Compressions: up.Compressions,
+	}, nil
+}
+
+func serverCredentials(t *TLS) (credentials.TransportCredentials, error) {
+	tc, err := serverTLS(t)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(tc), nil
+}
+
+func serverTLS(t *TLS) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if t.ClientCA != "" {
+		pool, err := loadPool(t.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+func clientCredentials(t *ClientTLS) (credentials.TransportCredentials, error) {
+	tc := &tls.Config{
+		ServerName: t.ServerName,
+	}
+
+	if t.CA != "" {
+		pool, err := loadPool(t.CA)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = pool
+	}
+
+	if t.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tc), nil
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificate in %v", path)
+	}
+	return pool, nil
+}
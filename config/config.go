@@ -0,0 +1,266 @@
+// Package config describes a linkage node in a yaml or json file
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config is the topology of a linkage node
+type Config struct {
+	Listen     string      `json:"listen" yaml:"listen"`
+	TLS        *TLS        `json:"tls" yaml:"tls"`
+	Auth       *Auth       `json:"auth" yaml:"auth"`
+	Upstreams  []Upstream  `json:"upstreams" yaml:"upstreams"`
+	Waiting    *Waiting    `json:"waiting" yaml:"waiting"`
+	Engine     Engine      `json:"engine" yaml:"engine"`
+	Store      string      `json:"store" yaml:"store"`
+	DeadLetter *DeadLetter `json:"dead_letter" yaml:"dead_letter"`
+	Metrics    *Metrics    `json:"metrics" yaml:"metrics"`
+	Tracing    *Tracing    `json:"tracing" yaml:"tracing"`
+	Admin      *Admin      `json:"admin" yaml:"admin"`
+	Gateway    *Gateway    `json:"gateway" yaml:"gateway"`
+	Drain      *Drain      `json:"drain" yaml:"drain"`
+
+	// Compressions allowed for jobs sent to downstreams,
+	// jobs smaller than MinCompressSize bytes are not compressed
+	Compressions    []string `json:"compressions" yaml:"compressions"`
+	MinCompressSize int      `json:"min_compress_size" yaml:"min_compress_size"`
+
+	// MaxJobSize is the max bytes of a compressed job from upstreams
+	// or submitted after decompressed, default is 16MB
+	MaxJobSize int `json:"max_job_size" yaml:"max_job_size"`
+	// MaxSubmitJobs is the max jobs received by a submit stream, default is 1000
+	MaxSubmitJobs int `json:"max_submit_jobs" yaml:"max_submit_jobs"`
+}
+
+// TLS is the certificate of the server,
+// client certificates signed by ClientCA are required if it is set
+type TLS struct {
+	Cert     string `json:"cert" yaml:"cert"`
+	Key      string `json:"key" yaml:"key"`
+	ClientCA string `json:"client_ca" yaml:"client_ca"`
+}
+
+// Auth is how to authenticate downstreams
+// Type is one of "passcode", "mtls", "hmac" and "apikey", default is "passcode".
+// passcode: any passcode is accepted if Passcodes is empty
+// mtls: Names limits the certificate names allowed
+// hmac: Secret signs the token which expires after MaxAge
+// apikey: Keys maps the key to the name of downstream
+type Auth struct {
+	Type      string            `json:"type" yaml:"type"`
+	Passcodes []string          `json:"passcodes" yaml:"passcodes"`
+	Names     []string          `json:"names" yaml:"names"`
+	Secret    string            `json:"secret" yaml:"secret"`
+	MaxAge    Duration          `json:"max_age" yaml:"max_age"`
+	Keys      map[string]string `json:"keys" yaml:"keys"`
+}
+
+// Upstream is the remote linkage to ask jobs from
+// TLS is nil to connect without TLS.
+// APIKey or HMAC are sent as credentials if they are set
+type Upstream struct {
+	Addr       string      `json:"addr" yaml:"addr"`
+	Code       string      `json:"code" yaml:"code"`
+	Topics     []string    `json:"topics" yaml:"topics"`
+	MaxAttempt int         `json:"max_attempt" yaml:"max_attempt"`
+	Window     int         `json:"window" yaml:"window"`
+	TLS        *ClientTLS  `json:"tls" yaml:"tls"`
+	APIKey     string      `json:"api_key" yaml:"api_key"`
+	HMAC       *HMACSigner `json:"hmac" yaml:"hmac"`
+
+	// Compressions accepted for jobs in preferred order
+	Compressions []string `json:"compressions" yaml:"compressions"`
+}
+
+// ClientTLS verifies the upstream by CA,
+// Cert and Key are the client certificate for mtls
+type ClientTLS struct {
+	CA         string `json:"ca" yaml:"ca"`
+	Cert       string `json:"cert" yaml:"cert"`
+	Key        string `json:"key" yaml:"key"`
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// HMACSigner signs token of Name by Secret
+type HMACSigner struct {
+	Name   string `json:"name" yaml:"name"`
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+// Waiting is the arguments of linkage.WaitFactory, each upstream waits by it
+// between attempts to reconnect and gives up after MaxRetry waits.
+// The wait is Init seconds multiplied by Grow on each attempt, up to a minute,
+// Init is 1 and Grow is 2 if they are not set, and MaxRetry 0 never gives up
+type Waiting struct {
+	Init     int `json:"init" yaml:"init"`
+	Grow     int `json:"grow" yaml:"grow"`
+	MaxRetry int `json:"max_retry" yaml:"max_retry"`
+}
+
+// defaults of Waiting not set
+const (
+	defaultWaitInit = 1
+	defaultWaitGrow = 2
+)
+
+// withDefaults fills the fields not set
+func (w Waiting) withDefaults() Waiting {
+	if w.Init < 1 {
+		w.Init = defaultWaitInit
+	}
+	if w.Grow < 1 {
+		w.Grow = defaultWaitGrow
+	}
+	return w
+}
+
+// Engine is the name of registered engine and its config
+type Engine struct {
+	Name   string                 `json:"name" yaml:"name"`
+	Config map[string]interface{} `json:"config" yaml:"config"`
+}
+
+// DeadLetter is where to put jobs given up, File or Forward address,
+// jobs are dropped if both are empty. Forward is served with the tls and auth of the node,
+// at most MaxQueued letters wait to be taken, the ones over it go to File if it is set
+type DeadLetter struct {
+	File        string `json:"file" yaml:"file"`
+	Forward     string `json:"forward" yaml:"forward"`
+	MaxQueued   int    `json:"max_queued" yaml:"max_queued"`
+	MaxAttempts int    `json:"max_attempts" yaml:"max_attempts"`
+}
+
+// Tracing is where to export spans, Exporter is "stdout" or "otlp",
+// Endpoint is the OTLP/HTTP traces endpoint, default is the local collector
+type Tracing struct {
+	Exporter string `json:"exporter" yaml:"exporter"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	Service  string `json:"service" yaml:"service"`
+}
+
+// Admin serves admin service, all requests are denied if Passcodes is empty
+type Admin struct {
+	Passcodes []string `json:"passcodes" yaml:"passcodes"`
+}
+
+// Gateway serves jobs over http and json at Listen,
+// it uses the same TLS and auth as the job service.
+// Browsers of Origins can call it besides the origin of the gateway
+type Gateway struct {
+	Listen  string   `json:"listen" yaml:"listen"`
+	Origins []string `json:"origins" yaml:"origins"`
+}
+
+// Drain is the deadline of each phase of draining when the node stops,
+// see linkage.DrainTimeouts, the default is used for the phase not set
+type Drain struct {
+	Upstream   Duration `json:"upstream" yaml:"upstream"`
+	Engine     Duration `json:"engine" yaml:"engine"`
+	Downstream Duration `json:"downstream" yaml:"downstream"`
+	Shutdown   Duration `json:"shutdown" yaml:"shutdown"`
+}
+
+// Metrics is where to serve prometheus metrics
+type Metrics struct {
+	Listen string `json:"listen" yaml:"listen"`
+	Path   string `json:"path" yaml:"path"`
+}
+
+// Duration is time.Duration written as "10s" in config
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	err := unmarshal(&s)
+	if err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) parse(s string) error {
+	if s == "" {
+		*d = 0
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load reads the config file, it is json if the extension is .json,
+// otherwise yaml
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(b, cfg)
+	} else {
+		err = yaml.Unmarshal(b, cfg)
+		if cfg.Engine.Config != nil {
+			cfg.Engine.Config = stringKeys(cfg.Engine.Config).(map[string]interface{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %v fail: %v", path, err)
+	}
+
+	if cfg.Listen == "" {
+		return nil, fmt.Errorf("listen address is required")
+	}
+	if cfg.Engine.Name == "" {
+		return nil, fmt.Errorf("engine name is required")
+	}
+	return cfg, nil
+}
+
+// stringKeys converts the map[interface{}]interface{} decoded by yaml
+// to map[string]interface{} as json does
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprintf("%v", k)] = stringKeys(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = stringKeys(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = stringKeys(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
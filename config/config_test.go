@@ -0,0 +1,265 @@
+package config
+
+import (
+	"io/ioutil"
+	"linkage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name string, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		ok      bool
+	}{
+		{"yaml", "node.yaml", `
+listen: ":8081"
+auth:
+  type: hmac
+  secret: s
+  max_age: 30s
+upstreams:
+  - addr: "localhost:8080"
+    topics: ["orders.#"]
+    window: 10
+engine:
+  name: webhook
+  config:
+    listen: ":8090"
+    headers:
+      x-mode: roundrobin
+`, true},
+		{"json", "node.json", `{
+  "listen": ":8081",
+  "auth": {"type": "hmac", "secret": "s", "max_age": "30s"},
+  "upstreams": [{"addr": "localhost:8080", "topics": ["orders.#"], "window": 10}],
+  "engine": {"name": "webhook", "config": {"listen": ":8090", "headers": {"x-mode": "roundrobin"}}}
+}`, true},
+		{"no listen", "node.yaml", "engine:\n  name: stdio\n", false},
+		{"no engine", "node.yaml", "listen: \":8081\"\n", false},
+		{"bad duration", "node.yaml", "listen: \":8081\"\nengine:\n  name: stdio\nauth:\n  max_age: soon\n", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, tc.file, tc.content))
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+			if !tc.ok {
+				return
+			}
+
+			if cfg.Listen != ":8081" || cfg.Auth.Type != "hmac" || time.Duration(cfg.Auth.MaxAge) != 30*time.Second {
+				t.Fatalf("got config %+v auth %+v", cfg, cfg.Auth)
+			}
+			if len(cfg.Upstreams) != 1 || cfg.Upstreams[0].Window != 10 || cfg.Upstreams[0].Topics[0] != "orders.#" {
+				t.Fatalf("got upstreams %+v", cfg.Upstreams)
+			}
+			// yaml maps are decoded with string keys as json
+			headers, ok := cfg.Engine.Config["headers"].(map[string]interface{})
+			if cfg.Engine.Name != "webhook" || !ok || headers["x-mode"] != "roundrobin" {
+				t.Fatalf("got engine %+v", cfg.Engine)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	cases := []struct {
+		name   string
+		auth   *Auth
+		assert bool
+		ok     bool
+	}{
+		{"default", nil, true, true},
+		{"passcode", &Auth{Type: "passcode", Passcodes: []string{"yo"}}, true, true},
+		{"mtls", &Auth{Type: "mtls"}, false, true},
+		{"hmac", &Auth{Type: "hmac", Secret: "s"}, false, true},
+		{"apikey", &Auth{Type: "apikey", Keys: map[string]string{"k": "node"}}, false, true},
+		{"unknown", &Auth{Type: "basic"}, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert, auth, err := authenticate(tc.auth)
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+			if !tc.ok {
+				return
+			}
+			// either the passcode is asserted or the authenticator is used
+			if tc.assert != (assert != nil) || tc.assert == (auth != nil) {
+				t.Fatalf("got code assert %v, authenticator %v", assert != nil, auth != nil)
+			}
+		})
+	}
+}
+
+func TestPasscodeAssert(t *testing.T) {
+	cases := []struct {
+		name      string
+		passcodes []string
+		code      linkage.Code
+		want      bool
+	}{
+		{"listed", []string{"yo", "hi"}, "hi", true},
+		{"not listed", []string{"yo"}, "hi", false},
+		{"prefix", []string{"hi"}, "h", false},
+		{"any", nil, "hi", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := passcodeAssert(tc.passcodes)(tc.code); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDialInfo(t *testing.T) {
+	di, err := dialInfo(Upstream{
+		Addr:       "localhost:8080",
+		Code:       "yo",
+		Topics:     []string{"orders.#"},
+		MaxAttempt: 3,
+		Window:     10,
+		APIKey:     "k",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Addr != "localhost:8080" || di.ConnCode != "yo" || di.MaxAttempt != 3 || di.Window != 10 || di.Topics[0] != "orders.#" {
+		t.Fatalf("got dial info %+v", di)
+	}
+	// insecure and the api key
+	if len(di.Opts) != 2 {
+		t.Fatalf("got %v dial options, want 2", len(di.Opts))
+	}
+
+	_, err = dialInfo(Upstream{
+		Addr: "localhost:8080",
+		TLS:  &ClientTLS{CA: "no-such-ca.pem"},
+	})
+	if err == nil {
+		t.Fatal("missing ca accepted")
+	}
+}
+
+// nopEngine sends nothing
+type nopEngine struct{}
+
+func (nopEngine) Start(<-chan *linkage.Job) error { return nil }
+
+func (nopEngine) Register(sig chan linkage.Signal) (<-chan *linkage.Job, error) {
+	return make(chan *linkage.Job), nil
+}
+
+func init() {
+	linkage.RegisterEngine("config-test", func(cfg map[string]interface{}) (linkage.Engine, error) {
+		return nopEngine{}, nil
+	})
+}
+
+func TestBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name string
+		cfg  *Config
+		ok   bool
+	}{
+		{"node", &Config{
+			Listen:    "127.0.0.1:0",
+			Upstreams: []Upstream{{Addr: "localhost:8080"}},
+			Waiting:   &Waiting{Init: 1, Grow: 2, MaxRetry: 3},
+			Store:     filepath.Join(dir, "jobs.log"),
+			Engine:    Engine{Name: "config-test"},
+		}, true},
+		{"unknown engine", &Config{
+			Listen: "127.0.0.1:0",
+			Engine: Engine{Name: "no-such-engine"},
+		}, false},
+		{"unknown auth", &Config{
+			Listen: "127.0.0.1:0",
+			Auth:   &Auth{Type: "basic"},
+			Engine: Engine{Name: "config-test"},
+		}, false},
+		{"missing server cert", &Config{
+			Listen: "127.0.0.1:0",
+			TLS:    &TLS{Cert: "no-such.crt", Key: "no-such.key"},
+			Engine: Engine{Name: "config-test"},
+		}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := Build(tc.cfg)
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+			if tc.ok && l == nil {
+				t.Fatal("no linkage built")
+			}
+		})
+	}
+}
+
+func TestWaitingDefaults(t *testing.T) {
+	cases := []struct {
+		w    Waiting
+		want Waiting
+	}{
+		{Waiting{}, Waiting{Init: 1, Grow: 2}},
+		{Waiting{Init: 3, MaxRetry: 5}, Waiting{Init: 3, Grow: 2, MaxRetry: 5}},
+		{Waiting{Init: 2, Grow: 3}, Waiting{Init: 2, Grow: 3}},
+	}
+	for _, tc := range cases {
+		if got := tc.w.withDefaults(); got != tc.want {
+			t.Errorf("defaults of %+v = %+v, want %+v", tc.w, got, tc.want)
+		}
+	}
+}
+
+func TestDeadLetterSpill(t *testing.T) {
+	file := writeConfig(t, "deadletters.log", "")
+	sink, err := deadLetter(&DeadLetter{
+		File:      file,
+		Forward:   "127.0.0.1:0",
+		MaxQueued: 10,
+	}, &linkage.ServerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// the forward sink spills to the file
+	f, ok := sink.(*linkage.ForwardDeadLetters)
+	if !ok {
+		t.Fatalf("got sink %T, want forward", sink)
+	}
+	if _, ok := f.Spill.(*linkage.FileDeadLetters); !ok || f.MaxQueued != 10 {
+		t.Fatalf("got spill %T, max queued %v", f.Spill, f.MaxQueued)
+	}
+}
@@ -0,0 +1,323 @@
+package linkage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Metadata keys of the dead letter set on the job forwarded by ForwardDeadLetters
+const (
+	MetaDeadReason   = "linkage_dead_reason"
+	MetaDeadAttempts = "linkage_dead_attempts"
+	MetaDeadError    = "linkage_dead_error"
+)
+
+// DeadLetter is a job failed to deliver or rejected by engine
+type DeadLetter struct {
+	Job       *Job      `json:"job"`
+	Reason    string    `json:"reason"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// DeadLetterSink receives the jobs linkage gives up
+type DeadLetterSink interface {
+	// Put keeps the dead letter
+	Put(dl *DeadLetter) error
+	// Close closes the sink
+	Close() error
+}
+
+// putDeadLetter puts the job to sink, the job is dropped if sink is nil
+func putDeadLetter(sink DeadLetterSink, m Metrics, j *Job, reason string, lastErr string) {
+	log.WithFields(log.Fields{
+		"id":       j.ID,
+		"reason":   reason,
+		"attempts": j.attempts,
+	}).Warnf("give up job, last error: %v", lastErr)
+	m.JobDropped("dead_letter")
+	if sink == nil {
+		return
+	}
+
+	err := sink.Put(&DeadLetter{
+		Job:       j,
+		Reason:    reason,
+		Attempts:  j.attempts,
+		LastError: lastErr,
+		FailedAt:  time.Now(),
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id": j.ID,
+		}).Errorf("put dead letter fail, error: %v", err)
+	}
+}
+
+// MemoryDeadLetters keeps the latest dead letters in memory
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	max     int
+	letters []*DeadLetter
+}
+
+// InitMemoryDeadLetters returns a MemoryDeadLetters keeps at most max dead letters,
+// the oldest one is dropped when it is full, no limit if max is less than 1
+func InitMemoryDeadLetters(max int) *MemoryDeadLetters {
+	return &MemoryDeadLetters{
+		max: max,
+	}
+}
+
+// Put implement DeadLetterSink interface
+func (m *MemoryDeadLetters) Put(dl *DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.letters = append(m.letters, dl)
+	if m.max > 0 && len(m.letters) > m.max {
+		m.letters[0] = nil
+		m.letters = m.letters[1:]
+	}
+	return nil
+}
+
+// Letters returns the dead letters kept
+func (m *MemoryDeadLetters) Letters() []*DeadLetter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*DeadLetter(nil), m.letters...)
+}
+
+// Drain returns and removes the dead letters kept
+func (m *MemoryDeadLetters) Drain() []*DeadLetter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	letters := m.letters
+	m.letters = nil
+	return letters
+}
+
+// Close implement DeadLetterSink interface
+func (m *MemoryDeadLetters) Close() error {
+	return nil
+}
+
+// FileDeadLetters appends dead letters to a file as json lines
+type FileDeadLetters struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenFileDeadLetters opens or creates the dead letter file at path
+func OpenFileDeadLetters(path string) (*FileDeadLetters, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileDeadLetters{
+		f: f,
+	}, nil
+}
+
+// Put implement DeadLetterSink interface
+func (s *FileDeadLetters) Put(dl *DeadLetter) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.f.Write(append(b, '\n'))
+	if err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close implement DeadLetterSink interface
+func (s *FileDeadLetters) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
+
+// ReadDeadLetters reads the dead letters in the file written by FileDeadLetters
+func ReadDeadLetters(path string) ([]*DeadLetter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []*DeadLetter
+	dec := json.NewDecoder(f)
+	for {
+		var dl DeadLetter
+		offset := dec.InputOffset()
+		err := dec.Decode(&dl)
+		if err == io.EOF {
+			return letters, nil
+		}
+		if err != nil {
+			// the last line is broken if the service crashed when writing it
+			log.WithFields(log.Fields{
+				"file":   path,
+				"offset": offset,
+			}).Warnf("skip broken dead letter, error: %v", err)
+			return letters, nil
+		}
+
+		letters = append(letters, &dl)
+	}
+}
+
+// ForwardDeadLetters serves dead letters at a separate address,
+// another linkage takes them by dialing the address as an upstream.
+// The reason, attempts and last error are set in metadata of the job.
+// MaxQueued is the max dead letters waiting to be taken, default is 10000,
+// the letters over it are put to Spill, or dropped if Spill is nil
+type ForwardDeadLetters struct {
+	MaxQueued int
+	Spill     DeadLetterSink
+
+	mu     sync.Mutex
+	server *Server
+	queue  *jobQueue
+	cancel context.CancelFunc
+}
+
+// defaultMaxForwardQueued is the MaxQueued if it is not set
+const defaultMaxForwardQueued = 10000
+
+// errForwardFull fails Put when the dead letters queued are at MaxQueued and Spill is nil
+var errForwardFull = errors.New("too many dead letters waiting to be forwarded")
+
+// InitForwardDeadLetters starts serving dead letters at addr
+func InitForwardDeadLetters(addr Addr, srvOpts []grpc.ServerOption, codeAssert CodeAssert) (*ForwardDeadLetters, error) {
+	return InitForwardDeadLettersConfig(&ServerConfig{
+		Addr:       addr,
+		SrvOpts:    srvOpts,
+		CodeAssert: codeAssert,
+	})
+}
+
+// InitForwardDeadLettersConfig starts serving dead letters by the server of cfg,
+// the engine in cfg is replaced by the sink. Set SrvOpts and Auth as the node
+// so dead letters are served with the same credentials
+func InitForwardDeadLettersConfig(cfg *ServerConfig) (*ForwardDeadLetters, error) {
+	f := &ForwardDeadLetters{
+		queue: newJobQueue(),
+	}
+
+	sc := *cfg
+	sc.Engine, sc.EngineV2 = f, nil
+	srv, err := InitServer(&sc)
+	if err != nil {
+		return nil, err
+	}
+	f.server = srv
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	go func() {
+		err := srv.RunContext(ctx)
+		if err != nil && err != context.Canceled {
+			log.Errorf("dead letter server stopped, error: %v", err)
+		}
+	}()
+	return f, nil
+}
+
+// Put implement DeadLetterSink interface
+func (f *ForwardDeadLetters) Put(dl *DeadLetter) error {
+	j := *dl.Job
+	j.Metadata = make(map[string]string, len(dl.Job.Metadata)+3)
+	for k, v := range dl.Job.Metadata {
+		j.Metadata[k] = v
+	}
+	j.Metadata[MetaDeadReason] = dl.Reason
+	j.Metadata[MetaDeadAttempts] = strconv.Itoa(dl.Attempts)
+	j.Metadata[MetaDeadError] = dl.LastError
+
+	max := f.MaxQueued
+	if max < 1 {
+		max = defaultMaxForwardQueued
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.queue.len() < max {
+		f.queue.push(&j)
+		return nil
+	}
+	if f.Spill == nil {
+		return errForwardFull
+	}
+	return f.Spill.Put(dl)
+}
+
+// Close implement DeadLetterSink interface, Spill is closed too
+func (f *ForwardDeadLetters) Close() error {
+	f.cancel()
+	<-f.server.Close()
+	if f.Spill != nil {
+		return f.Spill.Close()
+	}
+	return nil
+}
+
+// Start implement Engine interface
+func (f *ForwardDeadLetters) Start(<-chan *Job) error {
+	return nil
+}
+
+// Register implement Engine interface
+func (f *ForwardDeadLetters) Register(sig chan Signal) (<-chan *Job, error) {
+	out := make(chan *Job)
+	gone := make(chan struct{})
+	go func() {
+		for range sig {
+		}
+		close(gone)
+	}()
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-f.queue.ready:
+			case <-gone:
+				return
+			}
+
+			for j := f.queue.pop(); j != nil; j = f.queue.pop() {
+				select {
+				case out <- j:
+				case <-gone:
+					f.queue.push(j)
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
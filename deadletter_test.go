@@ -0,0 +1,201 @@
+package linkage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestMemoryDeadLetters(t *testing.T) {
+	m := InitMemoryDeadLetters(2)
+	for i := 0; i < 3; i++ {
+		m.Put(&DeadLetter{Job: CreateJob(strconv.Itoa(i), nil)})
+	}
+
+	// the oldest one is dropped
+	letters := m.Letters()
+	if len(letters) != 2 || letters[0].Job.Payload != "1" || letters[1].Job.Payload != "2" {
+		t.Fatalf("got %v letters", len(letters))
+	}
+	if len(m.Drain()) != 2 || len(m.Letters()) != 0 {
+		t.Fatal("letters kept after drain")
+	}
+}
+
+func TestFileDeadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dead.log")
+	s, err := OpenFileDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reason := range []string{"nacked", "rejected"} {
+		err = s.Put(&DeadLetter{
+			Job:       CreateJob(reason, nil),
+			Reason:    reason,
+			Attempts:  3,
+			LastError: "boom",
+			FailedAt:  time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	// the broken last line is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"job":{"id":`)
+	f.Close()
+
+	letters, err := ReadDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("got %v letters, want 2", len(letters))
+	}
+	for i, reason := range []string{"nacked", "rejected"} {
+		dl := letters[i]
+		if dl.Reason != reason || dl.Job.Payload != reason || dl.Attempts != 3 || dl.LastError != "boom" {
+			t.Errorf("got letter %+v", dl)
+		}
+	}
+}
+
+func TestServerDeadLetter(t *testing.T) {
+	sink := InitMemoryDeadLetters(0)
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:        addr,
+		Engine:      &testEngine{n: 1},
+		CodeAssert:  func(Code) bool { return true },
+		DeadLetter:  sink,
+		MaxAttempts: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	c := dialTest(t, &DialInfo{
+		Addr: addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	})
+	defer c.Close()
+
+	// the job is given up after nacked twice
+	var id string
+	for i := 0; i < 2; i++ {
+		j := askTimeout(t, c, time.Second)
+		if j == nil {
+			t.Fatalf("job not sent %v times", i+1)
+		}
+		id = j.ID
+		c.Nack(j.ID, "bad job")
+	}
+	if j := askTimeout(t, c, 300*time.Millisecond); j != nil {
+		t.Fatalf("job %v sent after max attempts", j.ID)
+	}
+
+	letters := sink.Letters()
+	if len(letters) != 1 {
+		t.Fatalf("got %v letters, want 1", len(letters))
+	}
+	dl := letters[0]
+	if dl.Job.ID != id || dl.Reason != "nacked" || dl.Attempts != 2 || dl.LastError != "bad job" {
+		t.Fatalf("got letter %+v", dl)
+	}
+}
+
+func TestForwardDeadLetters(t *testing.T) {
+	addr := freeAddr(t)
+	f, err := InitForwardDeadLetters(addr, nil, func(Code) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	j := CreateJob("p", map[string]string{"k": "v"})
+	f.Put(&DeadLetter{
+		Job:       j,
+		Reason:    "nacked",
+		Attempts:  3,
+		LastError: "boom",
+	})
+
+	c := dialTest(t, &DialInfo{
+		Addr: addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	})
+	defer c.Close()
+
+	got := askTimeout(t, c, time.Second)
+	if got == nil {
+		t.Fatal("dead letter not forwarded")
+	}
+	want := map[string]string{
+		"k":              "v",
+		MetaDeadReason:   "nacked",
+		MetaDeadAttempts: "3",
+		MetaDeadError:    "boom",
+	}
+	for k, v := range want {
+		if got.Metadata[k] != v {
+			t.Errorf("metadata %v is %q, want %q", k, got.Metadata[k], v)
+		}
+	}
+	// the metadata of the job put is intact
+	if len(j.Metadata) != 1 {
+		t.Errorf("metadata of the job put changed: %v", j.Metadata)
+	}
+}
+
+func TestForwardDeadLettersFull(t *testing.T) {
+	f, err := InitForwardDeadLetters(freeAddr(t), nil, func(Code) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.MaxQueued = 1
+	defer f.Close()
+
+	put := func(payload string) error {
+		return f.Put(&DeadLetter{
+			Job:    CreateJob(payload, nil),
+			Reason: "nacked",
+		})
+	}
+	if err := put("queued"); err != nil {
+		t.Fatal(err)
+	}
+	// the letter over MaxQueued is dropped without Spill
+	if err := put("dropped"); err != errForwardFull {
+		t.Fatalf("got error %v, want %v", err, errForwardFull)
+	}
+
+	spill := InitMemoryDeadLetters(0)
+	f.Spill = spill
+	if err := put("spilled"); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.queue.len(); n != 1 {
+		t.Fatalf("got %v letters queued, want 1", n)
+	}
+	if ls := spill.Letters(); len(ls) != 1 || ls[0].Job.Payload != "spilled" {
+		t.Fatalf("got spilled letters %v", ls)
+	}
+}
@@ -0,0 +1,229 @@
+// Package dispatch provides engines that distribute jobs
+// from a producer to the downstreams registered on linkage
+package dispatch
+
+import (
+	"errors"
+	"linkage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Producer produces jobs to out until it returns,
+// in is the jobs linkage recieved from upstreams
+type Producer func(in <-chan *linkage.Job, out chan<- *linkage.Job) error
+
+// pick returns the registrations the job should be sent to
+type pick func(regs []*registration, j *linkage.Job) []*registration
+
+// registration is a downstream registered to engine,
+// it takes all jobs if it subscribes no topic
+type registration struct {
+	topics []string
+	out    chan *linkage.Job
+	gone   chan struct{}
+}
+
+func (reg *registration) match(j *linkage.Job) bool {
+	if len(reg.topics) == 0 {
+		return true
+	}
+
+	for _, t := range reg.topics {
+		if linkage.MatchTopic(t, j.RoutingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatcher implements linkage.Engine and
+// sends the produced jobs to registrations picked by pick
+type dispatcher struct {
+	producer Producer
+	pick     pick
+	retry    bool
+	buffer   int
+	jobs     chan *linkage.Job
+	add      chan *registration
+	remove   chan *registration
+	stopped  chan struct{}
+}
+
+// retry is true if a job should be sent to others
+// when the picked registration is gone
+func newDispatcher(p Producer, pk pick, retry bool, buffer int) *dispatcher {
+	if buffer < 0 {
+		buffer = 0
+	}
+
+	d := &dispatcher{
+		producer: p,
+		pick:     pk,
+		retry:    retry,
+		buffer:   buffer,
+		jobs:     make(chan *linkage.Job),
+		add:      make(chan *registration),
+		remove:   make(chan *registration),
+		stopped:  make(chan struct{}),
+	}
+	go d.loop()
+	return d
+}
+
+// Start implements linkage.Engine, it runs the producer until it returns
+func (d *dispatcher) Start(inbound <-chan *linkage.Job) error {
+	defer close(d.jobs)
+	return d.producer(inbound, d.jobs)
+}
+
+// Register implements linkage.Engine
+func (d *dispatcher) Register(sig chan linkage.Signal) (<-chan *linkage.Job, error) {
+	return d.RegisterTopics(nil, sig)
+}
+
+// RegisterTopics implements linkage.TopicEngine,
+// the downstream is only sent jobs of the topics
+func (d *dispatcher) RegisterTopics(topics []string, sig chan linkage.Signal) (<-chan *linkage.Job, error) {
+	reg := &registration{
+		topics: topics,
+		out:    make(chan *linkage.Job, d.buffer),
+		gone:   make(chan struct{}),
+	}
+
+	select {
+	case d.add <- reg:
+	case <-d.stopped:
+		close(reg.out)
+		return reg.out, nil
+	}
+
+	// downstream is gone when it disconnects or closes the signal
+	go func() {
+		for s := range sig {
+			if s.Type == linkage.SignalDisconnected || s.Err != nil {
+				log.Infof("downstream gone, error: %v", s.Err)
+				break
+			}
+		}
+		close(reg.gone)
+
+		select {
+		case d.remove <- reg:
+		case <-d.stopped:
+		}
+	}()
+
+	return reg.out, nil
+}
+
+// delivery is the job being sent to the registrations picked,
+// one target at a time so registrations coming and going are not blocked
+type delivery struct {
+	job     *linkage.Job
+	targets []*registration
+	picked  bool
+	sent    bool
+}
+
+func (d *dispatcher) loop() {
+	defer close(d.stopped)
+
+	var regs []*registration
+	var pending *delivery
+	for {
+		// don't take new job until the pending one is sent
+		jobs := d.jobs
+		if pending != nil || len(regs) == 0 {
+			jobs = nil
+		}
+
+		// nil channels block, so nothing is sent without a target
+		var out chan *linkage.Job
+		var gone chan struct{}
+		var job *linkage.Job
+		if pending != nil && len(pending.targets) > 0 {
+			out, gone, job = pending.targets[0].out, pending.targets[0].gone, pending.job
+		}
+
+		select {
+		case reg := <-d.add:
+			regs = append(regs, reg)
+		case reg := <-d.remove:
+			regs = without(regs, reg)
+			if pending != nil {
+				pending.targets = without(pending.targets, reg)
+			}
+			close(reg.out)
+		case j, ok := <-jobs:
+			if !ok {
+				for _, reg := range regs {
+					close(reg.out)
+				}
+				return
+			}
+			pending = &delivery{job: j}
+		case out <- job:
+			pending.sent = true
+			pending.targets = pending.targets[1:]
+		case <-gone:
+			pending.targets = pending.targets[1:]
+		}
+
+		if pending != nil && len(pending.targets) == 0 {
+			pending = d.next(regs, pending)
+		}
+	}
+}
+
+var (
+	// errNoDownstream rejects the job no downstream took
+	errNoDownstream = errors.New("no downstream took the job")
+	// errNoSubscriber rejects the job no downstream subscribes
+	errNoSubscriber = errors.New("no downstream subscribes the job")
+)
+
+// next picks the registrations the job is sent to, it returns nil
+// once the job is done, or rejected if it is given up
+func (d *dispatcher) next(regs []*registration, p *delivery) *delivery {
+	switch {
+	case p.sent:
+		p.job.Done()
+		return nil
+	case p.picked && !d.retry:
+		p.job.Reject(errNoDownstream)
+		return nil
+	case len(regs) == 0:
+		// wait a registration to send the job again
+		return p
+	}
+
+	regs = subscribed(regs, p.job)
+	if len(regs) == 0 {
+		p.job.Reject(errNoSubscriber)
+		return nil
+	}
+	p.targets = d.pick(regs, p.job)
+	p.picked = true
+	return p
+}
+
+// subscribed returns the registrations match the job
+func subscribed(regs []*registration, j *linkage.Job) []*registration {
+	matched := make([]*registration, 0, len(regs))
+	for _, reg := range regs {
+		if reg.match(j) {
+			matched = append(matched, reg)
+		}
+	}
+	return matched
+}
+
+func without(regs []*registration, reg *registration) []*registration {
+	for i, r := range regs {
+		if r == reg {
+			return append(regs[:i], regs[i+1:]...)
+		}
+	}
+	return regs
+}
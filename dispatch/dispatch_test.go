@@ -0,0 +1,199 @@
+package dispatch
+
+import (
+	"fmt"
+	"linkage"
+	"testing"
+	"time"
+)
+
+// produce sends n jobs then returns, so the channels of downstreams are closed
+func produce(n int) Producer {
+	return func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+		for i := 0; i < n; i++ {
+			out <- linkage.CreateJob(fmt.Sprint(i), nil)
+		}
+		return nil
+	}
+}
+
+// register registers n downstreams, the signal of each is returned to close it
+func register(t *testing.T, e linkage.Engine, n int) ([]<-chan *linkage.Job, []chan linkage.Signal) {
+	var outs []<-chan *linkage.Job
+	var sigs []chan linkage.Signal
+	for i := 0; i < n; i++ {
+		sig := make(chan linkage.Signal)
+		out, err := e.Register(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outs = append(outs, out)
+		sigs = append(sigs, sig)
+	}
+	return outs, sigs
+}
+
+func count(out <-chan *linkage.Job) int {
+	n := 0
+	for range out {
+		n++
+	}
+	return n
+}
+
+// buffered waits until the jobs of want are in the channels
+func buffered(t *testing.T, outs []<-chan *linkage.Job, want []int) {
+	total := 0
+	for _, n := range want {
+		total += n
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		n := 0
+		for _, out := range outs {
+			n += len(out)
+		}
+		if n == total {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("jobs not sent")
+}
+
+func TestEngines(t *testing.T) {
+	cases := []struct {
+		name string
+		init func(p Producer, buffer int) linkage.Engine
+		jobs int
+		want []int
+	}{
+		{
+			name: "broadcast",
+			init: func(p Producer, buffer int) linkage.Engine { return InitBroadcastEngine(p, buffer) },
+			jobs: 3,
+			want: []int{3, 3},
+		},
+		{
+			name: "roundrobin",
+			init: func(p Producer, buffer int) linkage.Engine { return InitRoundRobinEngine(p, buffer) },
+			jobs: 4,
+			want: []int{2, 2},
+		},
+		{
+			name: "leastloaded",
+			init: func(p Producer, buffer int) linkage.Engine { return InitLeastLoadedEngine(p, buffer) },
+			jobs: 4,
+			want: []int{2, 2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := c.init(produce(c.jobs), c.jobs)
+			outs, _ := register(t, e, len(c.want))
+
+			err := e.Start(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the load is the jobs not read, so wait all jobs sent before reading
+			buffered(t, outs, c.want)
+			for i, out := range outs {
+				if got := count(out); got != c.want[i] {
+					t.Errorf("downstream %v got %v jobs, want %v", i, got, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundRobinGone(t *testing.T) {
+	jobs := make(chan *linkage.Job)
+	e := InitRoundRobinEngine(func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+		for j := range jobs {
+			out <- j
+		}
+		return nil
+	}, 4)
+	outs, sigs := register(t, e, 2)
+	go e.Start(nil)
+
+	// the downstream is gone when its signal channel is closed
+	close(sigs[0])
+	if _, ok := <-outs[0]; ok {
+		t.Fatal("job sent to the downstream gone")
+	}
+
+	for i := 0; i < 3; i++ {
+		jobs <- linkage.CreateJob(fmt.Sprint(i), nil)
+	}
+	close(jobs)
+	if got := count(outs[1]); got != 3 {
+		t.Fatalf("downstream left got %v jobs, want 3", got)
+	}
+}
+
+func TestTopics(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	e := InitBroadcastEngine(func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+		for _, key := range keys {
+			j := linkage.CreateJob(key, nil)
+			j.RoutingKey = key
+			out <- j
+		}
+		return nil
+	}, len(keys))
+
+	var outs []<-chan *linkage.Job
+	for _, topics := range [][]string{{"a"}, {"b", "c"}, nil} {
+		out, err := e.RegisterTopics(topics, make(chan linkage.Signal))
+		if err != nil {
+			t.Fatal(err)
+		}
+		outs = append(outs, out)
+	}
+
+	err := e.Start(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{1, 2, 3} {
+		if got := count(outs[i]); got != want {
+			t.Errorf("downstream %v got %v jobs, want %v", i, got, want)
+		}
+	}
+}
+
+func TestDispatchBlocked(t *testing.T) {
+	jobs := make(chan *linkage.Job)
+	e := InitBroadcastEngine(func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+		for j := range jobs {
+			out <- j
+		}
+		return nil
+	}, 0)
+	outs, _ := register(t, e, 1)
+	go e.Start(nil)
+	jobs <- linkage.CreateJob("0", nil)
+
+	// the downstream not reading doesn't block others registering
+	registered := make(chan error, 1)
+	go func() {
+		_, err := e.Register(make(chan linkage.Signal))
+		registered <- err
+	}()
+	select {
+	case err := <-registered:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("register blocked by the downstream not reading")
+	}
+
+	if j := <-outs[0]; j.Payload != "0" {
+		t.Fatalf("got %q, want 0", j.Payload)
+	}
+	close(jobs)
+}
@@ -0,0 +1,63 @@
+package dispatch
+
+import "linkage"
+
+// BroadcastEngine sends every job to all registered downstreams subscribing it
+type BroadcastEngine struct {
+	*dispatcher
+}
+
+// InitBroadcastEngine returns a BroadcastEngine runs the producer,
+// buffer is the size of channel to each downstream
+func InitBroadcastEngine(p Producer, buffer int) *BroadcastEngine {
+	all := func(regs []*registration, j *linkage.Job) []*registration {
+		return regs
+	}
+
+	return &BroadcastEngine{
+		dispatcher: newDispatcher(p, all, false, buffer),
+	}
+}
+
+// RoundRobinEngine sends each job to one registered downstream subscribing it in turn
+type RoundRobinEngine struct {
+	*dispatcher
+}
+
+// InitRoundRobinEngine returns a RoundRobinEngine runs the producer,
+// buffer is the size of channel to each downstream
+func InitRoundRobinEngine(p Producer, buffer int) *RoundRobinEngine {
+	next := 0
+	turn := func(regs []*registration, j *linkage.Job) []*registration {
+		next = (next + 1) % len(regs)
+		return regs[next : next+1]
+	}
+
+	return &RoundRobinEngine{
+		dispatcher: newDispatcher(p, turn, true, buffer),
+	}
+}
+
+// LeastLoadedEngine sends each job to the registered downstream
+// subscribing it with least jobs in its channel
+type LeastLoadedEngine struct {
+	*dispatcher
+}
+
+// InitLeastLoadedEngine returns a LeastLoadedEngine runs the producer,
+// buffer is the size of channel to each downstream
+func InitLeastLoadedEngine(p Producer, buffer int) *LeastLoadedEngine {
+	least := func(regs []*registration, j *linkage.Job) []*registration {
+		target := regs[0]
+		for _, reg := range regs[1:] {
+			if len(reg.out) < len(target.out) {
+				target = reg
+			}
+		}
+		return []*registration{target}
+	}
+
+	return &LeastLoadedEngine{
+		dispatcher: newDispatcher(p, least, true, buffer),
+	}
+}
@@ -0,0 +1,124 @@
+package linkage
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DrainTimeouts bounds each phase of draining when linkage stops,
+// a phase gives up when its deadline passes and the next one starts.
+// Upstream is how long to wait for the jobs being received from upstreams,
+// Engine is how long to wait for the queued jobs handed to engine,
+// Downstream is how long each stream flushes left jobs and waits for acks,
+// Shutdown is how long to wait for streams and the gateway to close.
+// The default is used for the phase not set
+type DrainTimeouts struct {
+	Upstream   time.Duration
+	Engine     time.Duration
+	Downstream time.Duration
+	Shutdown   time.Duration
+}
+
+// DefaultDrainTimeouts is used when WithDrainTimeouts is not set
+var DefaultDrainTimeouts = DrainTimeouts{
+	Upstream:   10 * time.Second,
+	Engine:     30 * time.Second,
+	Downstream: defaultDrainTimeout,
+	Shutdown:   2 * time.Minute,
+}
+
+// withDefaults fills the phases not set by DefaultDrainTimeouts
+func (t DrainTimeouts) withDefaults() DrainTimeouts {
+	if t.Upstream <= 0 {
+		t.Upstream = DefaultDrainTimeouts.Upstream
+	}
+	if t.Engine <= 0 {
+		t.Engine = DefaultDrainTimeouts.Engine
+	}
+	if t.Downstream <= 0 {
+		t.Downstream = DefaultDrainTimeouts.Downstream
+	}
+	if t.Shutdown <= 0 {
+		t.Shutdown = DefaultDrainTimeouts.Shutdown
+	}
+	return t
+}
+
+// drainPoll is how often the engine phase checks the queue
+const drainPoll = 50 * time.Millisecond
+
+// stopUpstreams stops asking jobs from upstreams and waits for the jobs
+// being received to be queued. Jobs sent by upstreams but not received yet
+// are redelivered by them
+func (s *Linkage) stopUpstreams(timeout time.Duration) {
+	s.drainMu.Lock()
+	close(s.draining)
+	s.drainMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.receiving.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("upstreams drained")
+	case <-time.After(timeout):
+		log.Warn("drain upstreams at timeup")
+	}
+}
+
+// closeUpstreams closes the clients after the queued jobs are handed to engine and acked,
+// upstreams redeliver the jobs left
+func (s *Linkage) closeUpstreams() {
+	for _, cli := range s.clients {
+		cli.Close()
+	}
+}
+
+// flushEngine waits for the queued jobs handed to engine
+func (s *Linkage) flushEngine(timeout time.Duration) {
+	tick := time.NewTicker(drainPoll)
+	defer tick.Stop()
+	deadline := time.After(timeout)
+
+	for s.pending.len() > 0 || len(s.slots) > 0 {
+		select {
+		case <-tick.C:
+		case <-deadline:
+			log.WithFields(log.Fields{
+				"queued": s.pending.len(),
+			}).Warn("flush engine at timeup")
+			return
+		}
+	}
+	log.Info("engine drained")
+}
+
+// beginReceive returns false if linkage is draining,
+// otherwise the job received is waited by stopUpstreams until endReceive
+func (s *Linkage) beginReceive() bool {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	if s.isDraining() {
+		return false
+	}
+	s.receiving.Add(1)
+	return true
+}
+
+func (s *Linkage) endReceive() {
+	s.receiving.Done()
+}
+
+func (s *Linkage) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
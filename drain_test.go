@@ -0,0 +1,151 @@
+package linkage
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestDrainTimeoutsDefaults(t *testing.T) {
+	got := DrainTimeouts{Engine: time.Second}.withDefaults()
+	want := DefaultDrainTimeouts
+	want.Engine = time.Second
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// idleEngine never takes jobs from upstreams
+type idleEngine struct{}
+
+func (idleEngine) Start(<-chan *Job) error {
+	select {}
+}
+
+func (idleEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	return make(chan *Job), nil
+}
+
+// stopTime stops the linkage and returns how long it takes
+func stopTime(l *Linkage) time.Duration {
+	start := time.Now()
+	l.Stop()
+	return time.Since(start)
+}
+
+func TestLinkageDrainEngine(t *testing.T) {
+	cases := []struct {
+		name    string
+		engine  Engine
+		timeout time.Duration
+		acked   bool
+		min     time.Duration
+		max     time.Duration
+	}{
+		// the jobs are acked to upstream once engine takes them
+		{"flushed", newCollectEngine(), 10 * time.Second, true, 0, 5 * time.Second},
+		// engine phase gives up at timeup, the jobs not taken are not acked
+		{"timeup", idleEngine{}, 300 * time.Millisecond, false, 300 * time.Millisecond, 5 * time.Second},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			const n = 3
+			up := &testEngine{n: n, sigs: make(chan Signal, 16)}
+			l, err := InitLinkage(freeAddr(t), tc.engine, nil, func(Code) bool { return true }, []*DialInfo{{
+				Addr: serveUpstream(t, up),
+				Opts: []grpc.DialOption{grpc.WithInsecure()},
+			}}, nil, WithDrainTimeouts(DrainTimeouts{
+				Engine:   tc.timeout,
+				Shutdown: time.Second,
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			go l.Run()
+
+			acked := 0
+			deadline := time.After(5 * time.Second)
+			for received := false; !received; {
+				select {
+				case s := <-up.sigs:
+					if s.Type == SignalAcked {
+						acked++
+					}
+				case <-time.After(20 * time.Millisecond):
+				case <-deadline:
+					t.Fatalf("%v of %v jobs acked to upstream", acked, n)
+				}
+				if tc.acked {
+					received = acked == n
+				} else {
+					// one job waits for engine to take it, the others are queued
+					received = l.pending.len() == n-1
+				}
+			}
+
+			if d := stopTime(l); d < tc.min || d > tc.max {
+				t.Fatalf("stop takes %v, want between %v and %v", d, tc.min, tc.max)
+			}
+			if e, ok := tc.engine.(*collectEngine); ok && len(e.got) != n {
+				t.Fatalf("engine took %v of %v jobs", len(e.got), n)
+			}
+			if !tc.acked && acked != 0 {
+				t.Fatalf("%v jobs not taken by engine acked to upstream", acked)
+			}
+		})
+	}
+}
+
+func TestServerDrainDownstream(t *testing.T) {
+	cases := []struct {
+		name string
+		ack  bool
+		min  time.Duration
+		max  time.Duration
+	}{
+		// the stream ends once the left job is acked
+		{"acked", true, 0, 400 * time.Millisecond},
+		// the stream waits for the ack until the drain timeout
+		{"unacked", false, 400 * time.Millisecond, 3 * time.Second},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			srv, err := InitServer(&ServerConfig{
+				Addr:         addr,
+				Engine:       &testEngine{n: 1},
+				CodeAssert:   func(Code) bool { return true },
+				DrainTimeout: 500 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			go srv.Run()
+
+			c := dialTest(t, &DialInfo{
+				Addr: addr,
+				Opts: []grpc.DialOption{grpc.WithInsecure()},
+			})
+			defer c.Close()
+			j := askTimeout(t, c, time.Second)
+			if j == nil {
+				t.Fatal("no job sent")
+			}
+
+			start := time.Now()
+			done := srv.Close()
+			if tc.ack {
+				c.Ack(j.ID)
+			}
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("server not closed")
+			}
+			if d := time.Since(start); d < tc.min || d > tc.max {
+				t.Fatalf("close takes %v, want between %v and %v", d, tc.min, tc.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package linkage
+
+import (
+	"context"
+
+	"google.golang.org/grpc/status"
+)
+
+// Engine processes jobs from upstreams and produces jobs to downstreams
+type Engine interface {
+	// Start starts the engine, jobs would send to the engine throught the inbound channel
+	Start(inbound <-chan *Job) error
+	// Register register an output destination, results generated by engine would send to the outbound channel
+	// the signal is used to notify down stream or service is closing
+	Register(sig chan Signal) (<-chan *Job, error)
+}
+
+// EngineV2 is an Engine with context, use AdaptEngine for an Engine
+type EngineV2 interface {
+	// Start starts the engine, ctx is canceled when linkage stopped after draining
+	Start(ctx context.Context, inbound <-chan *Job) error
+	// Register registers the downstream in info, ctx is canceled when its stream ends
+	Register(ctx context.Context, info RegisterInfo) (<-chan *Job, error)
+}
+
+// RegisterInfo is the downstream registering to EngineV2,
+// Signal is the lifecycle events of the downstream, see Signal
+type RegisterInfo struct {
+	Identity *Identity
+	Peer     string
+	Topics   []string
+	Signal   chan Signal
+}
+
+// TopicEngine is an Engine which sends each downstream the jobs of the topics it subscribes
+type TopicEngine interface {
+	Engine
+	// RegisterTopics is called instead of Register with the topics of downstream
+	RegisterTopics(topics []string, sig chan Signal) (<-chan *Job, error)
+}
+
+// AdaptEngine returns the EngineV2 calls e, the context is ignored.
+// RegisterIdentity is called if e is an IdentityEngine, or RegisterTopics if e is a TopicEngine
+func AdaptEngine(e Engine) EngineV2 {
+	return engineAdapter{
+		e: e,
+	}
+}
+
+type engineAdapter struct {
+	e Engine
+}
+
+func (a engineAdapter) Start(ctx context.Context, inbound <-chan *Job) error {
+	return a.e.Start(inbound)
+}
+
+func (a engineAdapter) Register(ctx context.Context, info RegisterInfo) (<-chan *Job, error) {
+	if e, ok := a.e.(IdentityEngine); ok {
+		return e.RegisterIdentity(info.Identity, info.Signal)
+	}
+	if e, ok := a.e.(TopicEngine); ok {
+		return e.RegisterTopics(info.Topics, info.Signal)
+	}
+	return a.e.Register(info.Signal)
+}
+
+// Done is closed when the work is done
+type Done = chan struct{}
+
+// SignalType is the type of lifecycle event of a downstream
+type SignalType int
+
+// Signal types, the signal channel is closed after SignalDisconnected.
+// At most 256 signals are queued for each downstream, new ones but SignalDisconnected
+// are dropped when it is full, and signals not taken 5 seconds after the stream ends are dropped
+const (
+	// SignalConnected is sent when downstream connected, Peer, Code and Identity are set
+	SignalConnected SignalType = iota + 1
+	// SignalPaused is sent when downstream runs out of credit
+	SignalPaused
+	// SignalResumed is sent when downstream grants credit after paused
+	SignalResumed
+	// SignalDraining is sent when server is closing and flushing left jobs
+	SignalDraining
+	// SignalDisconnected is sent when the stream ends, Status is set
+	// and Err is set if the stream ends by error
+	SignalDisconnected
+	// SignalAcked is sent when downstream acks a job, JobID is set
+	SignalAcked
+	// SignalNacked is sent when downstream nacks a job, JobID and Reason are set
+	SignalNacked
+	// SignalExpired is sent when a job past the deadline is not sent, JobID and Reason are set
+	SignalExpired
+)
+
+var signalTypeNames = map[SignalType]string{
+	SignalConnected:    "connected",
+	SignalPaused:       "paused",
+	SignalResumed:      "resumed",
+	SignalDraining:     "draining",
+	SignalDisconnected: "disconnected",
+	SignalAcked:        "acked",
+	SignalNacked:       "nacked",
+	SignalExpired:      "expired",
+}
+
+func (t SignalType) String() string {
+	name, ok := signalTypeNames[t]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// Signal is the event of a downstream sent to engine
+type Signal struct {
+	Type     SignalType
+	Err      error
+	Peer     string
+	Code     Code
+	Identity *Identity
+	Status   *status.Status
+	JobID    string
+	Reason   string
+}
@@ -0,0 +1,230 @@
+// Package engines provides built-in engines, they are registered
+// on linkage by name when the package is imported:
+//
+//	stdio    jobs from upstreams are written to stdout, lines of stdin are sent to downstreams
+//	tail     lines appended to a file are sent to downstreams
+//	webhook  bodies posted to an http endpoint are sent to downstreams
+//	exec     jobs are written to stdin of a command, lines of its stdout are sent to downstreams
+//
+// All of them take "mode" (broadcast, roundrobin or leastloaded, default roundrobin)
+// and "buffer" in config to decide how jobs are dispatched to downstreams.
+// stdio and exec fail when stdin is closed or the command exits, linkage stops then
+package engines
+
+import (
+	"fmt"
+	"linkage"
+	"linkage/dispatch"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	linkage.RegisterEngine("stdio", InitStdioEngine)
+	linkage.RegisterEngine("tail", InitTailEngine)
+	linkage.RegisterEngine("webhook", InitWebhookEngine)
+	linkage.RegisterEngine("exec", InitExecEngine)
+}
+
+// dispatchEngine creates the dispatch engine runs p by mode in config
+func dispatchEngine(cfg config, p dispatch.Producer) (linkage.Engine, error) {
+	buffer, err := cfg.int("buffer", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := cfg.string("mode", "roundrobin")
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case "broadcast":
+		return dispatch.InitBroadcastEngine(p, buffer), nil
+	case "roundrobin":
+		return dispatch.InitRoundRobinEngine(p, buffer), nil
+	case "leastloaded":
+		return dispatch.InitLeastLoadedEngine(p, buffer), nil
+	default:
+		return nil, fmt.Errorf("unknown dispatch mode %v", mode)
+	}
+}
+
+// passThrough sends jobs from upstreams to downstreams until quit is closed,
+// the dispatcher marks them done once sent or rejects them
+func passThrough(in <-chan *linkage.Job, out chan<- *linkage.Job, quit <-chan struct{}) {
+	for {
+		select {
+		case j := <-in:
+			select {
+			case out <- j:
+			case <-quit:
+				return
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// lines sends the lines as jobs to out until lines is closed or quit is closed,
+// source is set as metadata of the jobs
+func lines(ls <-chan string, out chan<- *linkage.Job, source string, quit <-chan struct{}) {
+	for l := range ls {
+		j := linkage.CreateJob(l, map[string]string{
+			linkage.MetaSource: source,
+		})
+
+		select {
+		case out <- j:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// NewJob creates the job of body, json and text body without encoding is kept
+// in Payload, others in Data with the content type and encoding
+func NewJob(body []byte, contentType, encoding string, meta map[string]string) *linkage.Job {
+	text := contentType == "" ||
+		strings.HasPrefix(contentType, linkage.ContentTypeJSON) ||
+		strings.HasPrefix(contentType, "text/")
+	if text && encoding == "" {
+		j := linkage.CreateJob(string(body), meta)
+		j.ContentType = contentType
+		return j
+	}
+
+	j := linkage.CreateBytesJob(body, contentType, meta)
+	j.Encoding = encoding
+	return j
+}
+
+// group runs goroutines and waits them to return after quit is closed,
+// so nothing is sent to out when producer returns
+type group struct {
+	wg   sync.WaitGroup
+	quit chan struct{}
+	once sync.Once
+}
+
+func newGroup() *group {
+	return &group{
+		quit: make(chan struct{}),
+	}
+}
+
+func (g *group) run(f func(quit <-chan struct{})) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		f(g.quit)
+	}()
+}
+
+func (g *group) stop() {
+	g.once.Do(func() {
+		close(g.quit)
+	})
+	g.wg.Wait()
+}
+
+// config is the config map of an engine
+type config map[string]interface{}
+
+func (c config) string(key, def string) (string, error) {
+	v, ok := c[key]
+	if !ok {
+		return def, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%v should be a string", key)
+	}
+	return s, nil
+}
+
+func (c config) int(key string, def int) (int, error) {
+	v, ok := c[key]
+	if !ok {
+		return def, nil
+	}
+
+	// json decodes numbers as float64
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("%v should be a number", key)
+	}
+}
+
+func (c config) bool(key string, def bool) (bool, error) {
+	v, ok := c[key]
+	if !ok {
+		return def, nil
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%v should be a bool", key)
+	}
+	return b, nil
+}
+
+// duration accepts a string like "1s" or a number of seconds
+func (c config) duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := c[key]
+	if !ok {
+		return def, nil
+	}
+
+	switch d := v.(type) {
+	case string:
+		return time.ParseDuration(d)
+	case int:
+		return time.Duration(d) * time.Second, nil
+	case float64:
+		return time.Duration(d * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("%v should be a duration", key)
+	}
+}
+
+func (c config) strings(key string) ([]string, error) {
+	v, ok := c[key]
+	if !ok {
+		return nil, nil
+	}
+
+	switch l := v.(type) {
+	case string:
+		return []string{l}, nil
+	case []string:
+		return l, nil
+	case []interface{}:
+		ss := make([]string, 0, len(l))
+		for _, e := range l {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v should be a list of string", key)
+			}
+			ss = append(ss, s)
+		}
+		return ss, nil
+	default:
+		return nil, fmt.Errorf("%v should be a list of string", key)
+	}
+}
+
+func logger(engine string) *log.Entry {
+	return log.WithFields(log.Fields{
+		"engine": engine,
+	})
+}
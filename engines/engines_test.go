@@ -0,0 +1,208 @@
+package engines
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"linkage"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		engine string
+		cfg    map[string]interface{}
+		ok     bool
+	}{
+		{"stdio", "stdio", nil, true},
+		{"exec", "exec", map[string]interface{}{"command": "cat"}, true},
+		{"exec no command", "exec", nil, false},
+		{"webhook no listen", "webhook", nil, false},
+		{"tail no path", "tail", nil, false},
+		{"tail bad interval", "tail", map[string]interface{}{"path": "x", "interval": true}, false},
+		{"broadcast", "stdio", map[string]interface{}{"mode": "broadcast", "buffer": 8}, true},
+		{"json buffer", "stdio", map[string]interface{}{"buffer": float64(8)}, true},
+		{"unknown mode", "stdio", map[string]interface{}{"mode": "random"}, false},
+		{"bad buffer", "stdio", map[string]interface{}{"buffer": "8"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := linkage.NewEngine(tc.engine, tc.cfg)
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+		})
+	}
+}
+
+// receive takes the jobs until the channel is closed
+func receive(t *testing.T, out <-chan *linkage.Job) []*linkage.Job {
+	var jobs []*linkage.Job
+	for {
+		select {
+		case j, ok := <-out:
+			if !ok {
+				return jobs
+			}
+			jobs = append(jobs, j)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("engine not stopped, got %v jobs", len(jobs))
+		}
+	}
+}
+
+func TestExecEngine(t *testing.T) {
+	cases := []struct {
+		name    string
+		command []interface{}
+		in      []string
+		want    []string
+	}{
+		{"stdout", []interface{}{"sh", "-c", "echo one; echo two"}, nil, []string{"one", "two"}},
+		{"stdin", []interface{}{"head", "-n", "1"}, []string{"hello"}, []string{"hello"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := InitExecEngine(map[string]interface{}{"command": tc.command})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := e.Register(make(chan linkage.Signal))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := make(chan *linkage.Job, len(tc.in))
+			for _, p := range tc.in {
+				in <- linkage.CreateJob(p, nil)
+			}
+			errc := make(chan error, 1)
+			go func() {
+				errc <- e.Start(in)
+			}()
+
+			// the engine fails when the command exits
+			jobs := receive(t, out)
+			if err := <-errc; err == nil || !strings.Contains(err.Error(), "exited") {
+				t.Fatalf("got error %v, want command exited", err)
+			}
+			if len(jobs) != len(tc.want) {
+				t.Fatalf("got %v jobs, want %v", len(jobs), tc.want)
+			}
+			for i, j := range jobs {
+				if j.Payload != tc.want[i] {
+					t.Errorf("job %v payload %q, want %q", i, j.Payload, tc.want[i])
+				}
+				if !strings.HasPrefix(j.Metadata[linkage.MetaSource], "exec:") {
+					t.Errorf("job %v source %q", i, j.Metadata[linkage.MetaSource])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	out := make(chan *linkage.Job, 1)
+	w := &webhook{out: out, quit: make(chan struct{})}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	rw := httptest.NewRecorder()
+	w.ServeHTTP(rw, r)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("get got status %v", rw.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("order 1"))
+	r.Header.Set(headerRoutingKey, "orders.new")
+	r.Header.Set("X-Meta-Tenant", "acme")
+	rw = httptest.NewRecorder()
+	w.ServeHTTP(rw, r)
+	if rw.Code != http.StatusAccepted {
+		t.Fatalf("post got status %v", rw.Code)
+	}
+
+	var res map[string]string
+	err := json.NewDecoder(rw.Body).Decode(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := <-out
+	if j.ID != res["id"] || j.Payload != "order 1" || j.RoutingKey != "orders.new" || j.Metadata["tenant"] != "acme" {
+		t.Fatalf("got job %+v, response %v", j, res)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", maxWebhookBody+1)))
+	rw = httptest.NewRecorder()
+	w.ServeHTTP(rw, r)
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("post of large body got status %v", rw.Code)
+	}
+	if len(out) != 0 {
+		t.Fatal("job of large body posted")
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	err = ioutil.WriteFile(path, []byte("old\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl := &tailer{path: path, interval: 10 * time.Millisecond}
+	ls := make(chan string)
+	quit := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tl.follow(ls, quit)
+	}()
+	next := func() string {
+		select {
+		case l := <-ls:
+			return l
+		case <-time.After(time.Second):
+			t.Fatal("no line followed")
+			return ""
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// wait the tailer to seek to the end
+	time.Sleep(50 * time.Millisecond)
+
+	// the partial line is sent after it is finished
+	f.WriteString("new ")
+	time.Sleep(30 * time.Millisecond)
+	f.WriteString("line\n")
+	if l := next(); l != "new line" {
+		t.Fatalf("got line %q, want %q", l, "new line")
+	}
+
+	// read from start again after truncated
+	f.Truncate(0)
+	f.WriteString("again\n")
+	if l := next(); l != "again" {
+		t.Fatalf("got line %q, want %q", l, "again")
+	}
+
+	close(quit)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,68 @@
+package engines
+
+import (
+	"errors"
+	"fmt"
+	"linkage"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// InitExecEngine returns an engine runs the command, payload of jobs from upstreams
+// are written to its stdin line by line and lines of its stdout are sent to downstreams,
+// it fails when the command exits.
+// Config: "command" is the command and its arguments
+func InitExecEngine(cfg map[string]interface{}) (linkage.Engine, error) {
+	c := config(cfg)
+	command, err := c.strings("command")
+	if err != nil {
+		return nil, err
+	}
+	if len(command) == 0 {
+		return nil, errors.New("command is required")
+	}
+
+	return dispatchEngine(c, func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+		return run(command, in, out)
+	})
+}
+
+func run(command []string, in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	logger("exec").Infof("command %v started", command[0])
+
+	g := newGroup()
+	g.run(func(quit <-chan struct{}) {
+		writeLines(in, stdin, "exec", quit)
+	})
+
+	ls, errc := readLines(stdout)
+	lines(ls, out, "exec:"+strings.Join(command, " "), g.quit)
+	err = <-errc
+
+	g.stop()
+	stdin.Close()
+	werr := cmd.Wait()
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	return fmt.Errorf("command %v exited", command[0])
+}
@@ -0,0 +1,115 @@
+package engines
+
+import (
+	"fmt"
+	"linkage"
+	"sync"
+	"time"
+)
+
+// FeedEngine sends a fixed set of jobs to downstreams until each of them
+// is acked or expired, such as dead letters to replay
+type FeedEngine struct {
+	jobs  chan *linkage.Job
+	mu    sync.Mutex
+	left  map[string]bool
+	total int
+	done  chan struct{}
+}
+
+// InitFeedEngine returns a FeedEngine sends the jobs, they should have ids
+func InitFeedEngine(jobs []*linkage.Job) *FeedEngine {
+	e := &FeedEngine{
+		jobs: make(chan *linkage.Job, len(jobs)),
+		left: make(map[string]bool, len(jobs)),
+		done: make(chan struct{}),
+	}
+	for _, j := range jobs {
+		e.jobs <- j
+		e.left[j.ID] = true
+	}
+	e.total = len(e.left)
+	if e.total == 0 {
+		close(e.done)
+	}
+	return e
+}
+
+// Start implements linkage.Engine, jobs from upstreams are ignored
+func (e *FeedEngine) Start(<-chan *linkage.Job) error {
+	return nil
+}
+
+// Register implements linkage.Engine
+func (e *FeedEngine) Register(sig chan linkage.Signal) (<-chan *linkage.Job, error) {
+	go func() {
+		for s := range sig {
+			// expired jobs are dropped by server, they never get acked
+			if s.Type == linkage.SignalAcked || s.Type == linkage.SignalExpired {
+				e.finish(s.JobID)
+			}
+		}
+	}()
+	return e.jobs, nil
+}
+
+func (e *FeedEngine) finish(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.left[id] {
+		return
+	}
+	delete(e.left, id)
+	if len(e.left) == 0 {
+		close(e.done)
+	}
+}
+
+// Done is closed when all jobs are acked or expired
+func (e *FeedEngine) Done() <-chan struct{} {
+	return e.done
+}
+
+// Left returns the number of jobs not acked or expired yet
+func (e *FeedEngine) Left() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.left)
+}
+
+// Serve runs the server of cfg with the engine until all jobs are acked or expired,
+// it gives up after timeout if timeout is greater than 0
+func (e *FeedEngine) Serve(cfg *linkage.ServerConfig, timeout time.Duration) error {
+	cfg.Engine = e
+	srv, err := linkage.InitServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Run()
+	}()
+	logger("feed").Infof("serving %v jobs at %v", e.total, cfg.Addr)
+
+	var timeup <-chan time.Time
+	if timeout > 0 {
+		timeup = time.After(timeout)
+	}
+
+	select {
+	case <-e.done:
+		err = nil
+	case err = <-errc:
+		return err
+	case <-timeup:
+		err = fmt.Errorf("%v of %v jobs not acked after %v", e.Left(), e.total, timeout)
+	}
+
+	select {
+	case <-srv.Close():
+	case <-time.After(5 * time.Second):
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package engines
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"linkage"
+	"os"
+	"strings"
+)
+
+// errStdinClosed fails the engine, no job comes from stdin any more
+var errStdinClosed = errors.New("stdin closed")
+
+// InitStdioEngine returns an engine writes payload of jobs from upstreams
+// to stdout line by line and sends lines of stdin to downstreams,
+// it fails when stdin is closed
+func InitStdioEngine(cfg map[string]interface{}) (linkage.Engine, error) {
+	return dispatchEngine(cfg, stdio(os.Stdin, os.Stdout))
+}
+
+func stdio(r io.Reader, w io.Writer) func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+	return func(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+		g := newGroup()
+		defer g.stop()
+
+		g.run(func(quit <-chan struct{}) {
+			writeLines(in, w, "stdio", quit)
+		})
+
+		ls, errc := readLines(r)
+		lines(ls, out, "stdin", g.quit)
+		err := <-errc
+		if err == nil {
+			return errStdinClosed
+		}
+		return err
+	}
+}
+
+// writeLines writes payload of jobs to w, a job is done when it is written
+// and rejected if it fails to be written
+func writeLines(in <-chan *linkage.Job, w io.Writer, engine string, quit <-chan struct{}) {
+	bw := bufio.NewWriter(w)
+	for {
+		select {
+		case j := <-in:
+			b, err := j.PayloadBytes()
+			if err == nil {
+				_, err = bw.Write(append(bytes.TrimSuffix(b, []byte("\n")), '\n'))
+			}
+			if err == nil {
+				err = bw.Flush()
+			}
+			if err != nil {
+				logger(engine).Errorf("write job %v fail, error: %v", j.ID, err)
+				j.Reject(err)
+				continue
+			}
+			j.Done()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// readLines reads lines from r until EOF, the error is nil if it ends by EOF
+func readLines(r io.Reader) (<-chan string, <-chan error) {
+	ls := make(chan string)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(ls)
+		br := bufio.NewReader(r)
+		for {
+			l, err := br.ReadString('\n')
+			if l != "" {
+				ls <- strings.TrimRight(l, "\r\n")
+			}
+			if err == io.EOF {
+				errc <- nil
+				return
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+		}
+	}()
+
+	return ls, errc
+}
@@ -0,0 +1,155 @@
+package engines
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"linkage"
+	"os"
+	"strings"
+	"time"
+)
+
+// InitTailEngine returns an engine sends lines appended to the file to downstreams,
+// jobs from upstreams are passed to downstreams.
+// Config: "path" of the file, "from_start" reads the file from start
+// instead of end, "interval" to check the file, default 1s
+func InitTailEngine(cfg map[string]interface{}) (linkage.Engine, error) {
+	c := config(cfg)
+	path, err := c.string("path", "")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+
+	fromStart, err := c.bool("from_start", false)
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := c.duration("interval", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tailer{
+		path:      path,
+		fromStart: fromStart,
+		interval:  interval,
+	}
+	return dispatchEngine(c, t.produce)
+}
+
+// tailer follows the file, it reads the file from start again
+// when the file is truncated or replaced
+type tailer struct {
+	path      string
+	fromStart bool
+	interval  time.Duration
+}
+
+func (t *tailer) produce(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+	g := newGroup()
+	defer g.stop()
+
+	g.run(func(quit <-chan struct{}) {
+		passThrough(in, out, quit)
+	})
+
+	ls := make(chan string)
+	errc := make(chan error, 1)
+	g.run(func(quit <-chan struct{}) {
+		defer close(ls)
+		errc <- t.follow(ls, quit)
+	})
+
+	lines(ls, out, t.path, g.quit)
+	return <-errc
+}
+
+func (t *tailer) follow(ls chan<- string, quit <-chan struct{}) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+	}()
+
+	if !t.fromStart {
+		_, err = f.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+	}
+
+	br := bufio.NewReader(f)
+	partial := ""
+	for {
+		l, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		// keep the partial line until it is finished
+		if err == io.EOF {
+			partial += l
+		} else {
+			select {
+			case ls <- strings.TrimRight(partial+l, "\r\n"):
+			case <-quit:
+				return nil
+			}
+			partial = ""
+			continue
+		}
+
+		select {
+		case <-time.After(t.interval):
+		case <-quit:
+			return nil
+		}
+
+		reopen, err := t.rotated(f)
+		if err != nil {
+			logger("tail").Errorf("check %v fail, error: %v", t.path, err)
+			continue
+		}
+		if reopen {
+			logger("tail").Infof("%v is truncated or replaced, read from start", t.path)
+			nf, err := os.Open(t.path)
+			if err != nil {
+				logger("tail").Errorf("reopen %v fail, error: %v", t.path, err)
+				continue
+			}
+			f.Close()
+			f = nf
+			br.Reset(f)
+			partial = ""
+		}
+	}
+}
+
+// rotated returns true if the file is truncated or the path is another file
+func (t *tailer) rotated(f *os.File) (bool, error) {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return false, err
+	}
+
+	cur, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(fi, cur) {
+		return true, nil
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	return fi.Size() < offset, nil
+}
@@ -0,0 +1,115 @@
+package engines
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"linkage"
+	"net/http"
+	"strings"
+)
+
+// headerRoutingKey is the header of routing key of the posted job
+const headerRoutingKey = "X-Routing-Key"
+
+// metaPrefix is the prefix of headers set as metadata of the posted job
+const metaPrefix = "X-Meta-"
+
+// maxWebhookBody is the max size of the body posted to the webhook
+const maxWebhookBody = 16 << 20
+
+// InitWebhookEngine returns an engine sends bodies posted to the http endpoint
+// to downstreams, jobs from upstreams are passed to downstreams.
+// Config: "listen" address, "path" of the endpoint, default "/"
+func InitWebhookEngine(cfg map[string]interface{}) (linkage.Engine, error) {
+	c := config(cfg)
+	listen, err := c.string("listen", "")
+	if err != nil {
+		return nil, err
+	}
+	if listen == "" {
+		return nil, errors.New("listen is required")
+	}
+
+	path, err := c.string("path", "/")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &webhook{
+		listen: listen,
+		path:   path,
+	}
+	return dispatchEngine(c, w.produce)
+}
+
+type webhook struct {
+	listen string
+	path   string
+	out    chan<- *linkage.Job
+	quit   <-chan struct{}
+}
+
+func (w *webhook) produce(in <-chan *linkage.Job, out chan<- *linkage.Job) error {
+	g := newGroup()
+	defer g.stop()
+
+	g.run(func(quit <-chan struct{}) {
+		passThrough(in, out, quit)
+	})
+
+	w.out, w.quit = out, g.quit
+	mux := http.NewServeMux()
+	mux.Handle(w.path, w)
+	srv := &http.Server{
+		Addr:    w.listen,
+		Handler: mux,
+	}
+	defer srv.Shutdown(context.Background())
+
+	logger("webhook").Infof("start listening %v", w.listen)
+	return srv.ListenAndServe()
+}
+
+// ServeHTTP accepts the posted body as payload of a job,
+// the job is accepted when it is taken by engine
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	meta := map[string]string{
+		linkage.MetaSource: r.RemoteAddr,
+	}
+	for k := range r.Header {
+		if strings.HasPrefix(k, metaPrefix) {
+			meta[strings.ToLower(strings.TrimPrefix(k, metaPrefix))] = r.Header.Get(k)
+		}
+	}
+
+	j := NewJob(body, r.Header.Get("Content-Type"), r.Header.Get("Content-Encoding"), meta)
+	j.RoutingKey = r.Header.Get(headerRoutingKey)
+
+	select {
+	case w.out <- j:
+	case <-w.quit:
+		http.Error(rw, "engine stopped", http.StatusServiceUnavailable)
+		return
+	case <-r.Context().Done():
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(rw).Encode(map[string]string{
+		"id": j.ID,
+	})
+}
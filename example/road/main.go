@@ -21,7 +21,7 @@ func main() {
 		MaxAttempt: 2,
 	}
 
-	srv, err := linkage.InitLinkage(addr, engine, nil, codeAssert, di, nil)
+	srv, err := linkage.InitLinkage(addr, engine, nil, codeAssert, []*linkage.DialInfo{di}, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,113 @@
+package linkage
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestJobExpired(t *testing.T) {
+	cases := []struct {
+		name     string
+		deadline time.Time
+		want     bool
+	}{
+		{"no deadline", time.Time{}, false},
+		{"future", time.Now().Add(time.Minute), false},
+		{"past", time.Now().Add(-time.Second), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			j := CreateJob("p", nil)
+			j.Deadline = tc.deadline
+			if got := j.Expired(); got != tc.want {
+				t.Fatalf("got expired %v, want %v", got, tc.want)
+			}
+
+			// the deadline is kept over grpc
+			back := toLinkageJob(toGRPCJob(j))
+			if !back.Deadline.Equal(j.Deadline) || !back.CreatedAt.Equal(j.CreatedAt) {
+				t.Fatalf("got deadline %v created %v, want %v %v",
+					back.Deadline, back.CreatedAt, j.Deadline, j.CreatedAt)
+			}
+		})
+	}
+}
+
+// jobsEngine sends the jobs to the first downstream and forwards the signals to sigs
+type jobsEngine struct {
+	jobs []*Job
+	sigs chan Signal
+}
+
+func (e *jobsEngine) Start(<-chan *Job) error { return nil }
+
+func (e *jobsEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	jobs := make(chan *Job, len(e.jobs))
+	for _, j := range e.jobs {
+		jobs <- j
+	}
+	e.jobs = nil
+	go func() {
+		for s := range sig {
+			e.sigs <- s
+		}
+	}()
+	return jobs, nil
+}
+
+func TestServerExpired(t *testing.T) {
+	old := CreateJob("old", nil)
+	old.Deadline = time.Now().Add(-time.Second)
+	live := CreateJob("live", nil)
+	live.Deadline = time.Now().Add(time.Minute)
+	e := &jobsEngine{
+		jobs: []*Job{old, live},
+		sigs: make(chan Signal, 16),
+	}
+
+	expired := make(chan *Job, 1)
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:       addr,
+		Engine:     e,
+		CodeAssert: func(Code) bool { return true },
+		Expired:    func(j *Job) { expired <- j },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	c := dialTest(t, &DialInfo{
+		Addr: addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	})
+	defer c.Close()
+
+	j := askTimeout(t, c, time.Second)
+	if j.GetID() != live.ID {
+		t.Fatalf("got job %v, want the live one %v", j.GetID(), live.ID)
+	}
+	select {
+	case j := <-expired:
+		if j.ID != old.ID {
+			t.Fatalf("got expired job %v, want %v", j.ID, old.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expired handler not called")
+	}
+
+	for {
+		select {
+		case s := <-e.sigs:
+			if s.Type == SignalExpired && s.JobID == old.ID {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expired signal not sent")
+		}
+	}
+}
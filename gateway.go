@@ -0,0 +1,498 @@
+package linkage
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"linkage/proto/job"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// HeaderCode is the http header of the passcode sent to the gateway,
+// it can also be sent by query parameter "code", which ends up in access logs
+// of proxies in front of the gateway, so prefer the header when it can be set
+const HeaderCode = "X-Linkage-Code"
+
+// maxGatewayBody is the max size of the body posted to the gateway
+const maxGatewayBody = 16 << 20
+
+// gateway serves Submit and Ask of the server over http and json.
+// POST /jobs takes a job or an array of jobs in json and returns the receipts,
+// GET /jobs/stream streams jobs as server-sent events if the request
+// accepts text/event-stream, otherwise as json lines.
+// Jobs on the stream are acked once they are written to the response.
+// /jobs/ws streams jobs over websocket, see wsStream.
+// Requests from browsers of other origins than the gateway and origins are refused,
+// browsers send client certificates and cookies for them too
+type gateway struct {
+	server  *Server
+	srv     *http.Server
+	origins []string
+}
+
+func newGateway(addr Addr, tc *tls.Config, server *Server, origins []string) *gateway {
+	g := &gateway{
+		server:  server,
+		origins: origins,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", g.submit)
+	mux.HandleFunc("/jobs/stream", g.ask)
+	mux.HandleFunc("/jobs/ws", g.websocket)
+	g.srv = &http.Server{
+		Addr:      addr,
+		Handler:   g.checkOrigin(mux),
+		TLSConfig: tc,
+	}
+	return g
+}
+
+// checkOrigin refuses requests from origins not allowed
+func (g *gateway) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.allowOrigin(r) {
+			httpError(w, http.StatusForbidden, "origin not allowed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowOrigin allows requests without Origin which are not from browsers,
+// requests from the gateway itself and from origins, "*" allows any origin
+func (g *gateway) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, o := range g.origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// run serves until close is called
+func (g *gateway) run() error {
+	log.Infof("start http gateway %v", g.srv.Addr)
+
+	var err error
+	if g.srv.TLSConfig != nil {
+		err = g.srv.ListenAndServeTLS("", "")
+	} else {
+		err = g.srv.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// close waits the requests in flight until timeout then closes the connections
+func (g *gateway) close(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := g.srv.Shutdown(ctx)
+	if err != nil {
+		g.srv.Close()
+	}
+}
+
+type submitResponse struct {
+	Receipts []Receipt `json:"receipts"`
+}
+
+func (g *gateway) submit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		httpError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if g.server.shouldClose() {
+		httpError(w, http.StatusServiceUnavailable, "server is closing")
+		return
+	}
+
+	ctx := grpcContext(r)
+	_, err := g.server.authenticate(ctx, requestCode(r))
+	if err != nil {
+		httpStatusError(w, err)
+		return
+	}
+
+	jobs, err := readJobs(http.MaxBytesReader(w, r.Body, maxGatewayBody))
+	if err != nil {
+		httpError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the traceparent header is the parent of jobs not traced
+	var trace SpanContext
+	if tp := r.Header.Get(MetaTraceParent); tp != "" {
+		trace, _ = ParseTraceParent(tp)
+	}
+
+	source := peerAddr(ctx)
+	resp := &submitResponse{
+		Receipts: make([]Receipt, 0, len(jobs)),
+	}
+	code := http.StatusAccepted
+	for _, j := range jobs {
+		if !j.Trace.IsValid() {
+			j.Trace = trace
+		}
+
+		rc := g.server.acceptJob(j, source)
+		if !rc.Accepted {
+			code = http.StatusUnprocessableEntity
+		}
+		resp.Receipts = append(resp.Receipts, rc)
+	}
+
+	writeJSON(w, code, resp)
+}
+
+// readJobs reads a job or an array of jobs
+func readJobs(r io.Reader) ([]*Job, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var jobs []*Job
+		err = json.Unmarshal(b, &jobs)
+		return jobs, err
+	}
+
+	var j Job
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		return nil, err
+	}
+	return []*Job{&j}, nil
+}
+
+func (g *gateway) ask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		httpError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	if g.server.shouldClose() {
+		httpError(w, http.StatusServiceUnavailable, "server is closing")
+		return
+	}
+
+	// authenticate before the response starts, so the status can tell
+	ctx := grpcContext(r)
+	code := requestCode(r)
+	_, err := g.server.authenticate(ctx, code)
+	if err != nil {
+		httpStatusError(w, err)
+		return
+	}
+
+	stream := newHTTPStream(ctx, w, flusher, strings.Contains(r.Header.Get("Accept"), "text/event-stream"))
+	stream.pass = &job.Feedback{
+		Passphrase: &job.Passphrase{
+			Code:   code,
+			Topics: r.URL.Query()["topic"],
+		},
+	}
+	stream.start()
+
+	err = g.server.Ask(stream)
+	if status.Code(err) != codes.Canceled {
+		stream.fail(err)
+	}
+}
+
+// httpStream adapts the response to job.Service_AskServer,
+// it acks each job once it is written
+type httpStream struct {
+	streamHeader
+	ctx     context.Context
+	w       http.ResponseWriter
+	flusher http.Flusher
+	sse     bool
+	pass    *job.Feedback
+	acks    *ackQueue
+}
+
+func newHTTPStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, sse bool) *httpStream {
+	return &httpStream{
+		ctx:     ctx,
+		w:       w,
+		flusher: flusher,
+		sse:     sse,
+		acks:    newAckQueue(),
+	}
+}
+
+// start sends the header so the client knows the stream is open
+func (s *httpStream) start() {
+	if s.sse {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+	s.flusher.Flush()
+}
+
+// Send writes the job then acks it
+func (s *httpStream) Send(gj *job.Job) error {
+	err := decompressJob(gj, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(toLinkageJob(gj))
+	if err != nil {
+		return err
+	}
+
+	err = s.write("job", gj.GetId(), b)
+	if err != nil {
+		return err
+	}
+
+	s.acks.push(gj.GetId())
+	return nil
+}
+
+// fail tells the client why the stream ends
+func (s *httpStream) fail(err error) {
+	s.write("error", "", errorJSON(err))
+}
+
+func (s *httpStream) write(event, id string, b []byte) error {
+	var buf bytes.Buffer
+	if s.sse {
+		buf.WriteString("event: " + event + "\n")
+		if id != "" {
+			buf.WriteString("id: " + id + "\n")
+		}
+		buf.WriteString("data: ")
+		buf.Write(b)
+		buf.WriteString("\n\n")
+	} else {
+		buf.Write(b)
+		buf.WriteString("\n")
+	}
+
+	_, err := s.w.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// Recv returns the passphrase first, then the acks of jobs written
+func (s *httpStream) Recv() (*job.Feedback, error) {
+	if s.pass != nil {
+		fb := s.pass
+		s.pass = nil
+		return fb, nil
+	}
+
+	fb, ok := s.acks.pop(s.ctx.Done(), nil)
+	if !ok {
+		return nil, io.EOF
+	}
+	return fb, nil
+}
+
+func (s *httpStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *httpStream) SendMsg(m interface{}) error {
+	return s.Send(m.(*job.Job))
+}
+
+func (s *httpStream) RecvMsg(m interface{}) error {
+	fb, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	*m.(*job.Feedback) = *fb
+	return nil
+}
+
+// ackQueue keeps the ids of jobs written to the client, they are acked by Recv
+type ackQueue struct {
+	mu     sync.Mutex
+	ids    []string
+	pushed chan struct{}
+}
+
+func newAckQueue() *ackQueue {
+	return &ackQueue{
+		pushed: make(chan struct{}, 1),
+	}
+}
+
+// push never blocks, Send and Recv of the stream run in different goroutines
+func (q *ackQueue) push(id string) {
+	q.mu.Lock()
+	q.ids = append(q.ids, id)
+	q.mu.Unlock()
+
+	select {
+	case q.pushed <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for an ack or the feedback from other, it returns false if quit is closed
+func (q *ackQueue) pop(quit <-chan struct{}, other <-chan *job.Feedback) (*job.Feedback, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.ids) > 0 {
+			id := q.ids[0]
+			q.ids = q.ids[1:]
+			q.mu.Unlock()
+			return &job.Feedback{
+				Ack: &job.Ack{
+					Id: id,
+				},
+			}, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.pushed:
+		case fb := <-other:
+			return fb, true
+		case <-quit:
+			return nil, false
+		}
+	}
+}
+
+// streamHeader implements the header methods of grpc.ServerStream, there is no header to send
+type streamHeader struct{}
+
+func (streamHeader) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (streamHeader) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (streamHeader) SetTrailer(metadata.MD) {}
+
+// grpcContext returns the context carries the peer and headers of the request
+// as grpc does, so the request is authenticated the same way
+func grpcContext(r *http.Request) context.Context {
+	p := &peer.Peer{
+		Addr: httpAddr(r.RemoteAddr),
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{
+			State: *r.TLS,
+		}
+	}
+
+	md := metadata.MD{}
+	for k, vs := range r.Header {
+		md[strings.ToLower(k)] = vs
+	}
+
+	ctx := peer.NewContext(r.Context(), p)
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func requestCode(r *http.Request) Code {
+	if code := r.Header.Get(HeaderCode); code != "" {
+		return code
+	}
+	return r.URL.Query().Get("code")
+}
+
+// httpAddr is the remote address of the request
+type httpAddr string
+
+func (a httpAddr) Network() string {
+	return "tcp"
+}
+
+func (a httpAddr) String() string {
+	return string(a)
+}
+
+// errorJSON is the error ends the stream in json
+func errorJSON(err error) []byte {
+	st := status.Convert(err)
+	b, _ := json.Marshal(map[string]string{
+		"error": st.Message(),
+		"code":  st.Code().String(),
+	})
+	return b
+}
+
+func httpStatusError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument, codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.Unavailable, codes.Aborted:
+		code = http.StatusServiceUnavailable
+	}
+	httpError(w, code, st.Message())
+}
+
+func httpError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{
+		"error": msg,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Errorf("write response fail, error: %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package linkage
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// relayEngine sends the jobs linkage takes to its downstream
+type relayEngine struct {
+	out chan *Job
+}
+
+func newRelayEngine() *relayEngine {
+	return &relayEngine{
+		out: make(chan *Job),
+	}
+}
+
+func (e *relayEngine) Start(in <-chan *Job) error {
+	for j := range in {
+		e.out <- j
+	}
+	return nil
+}
+
+func (e *relayEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	go func() {
+		for range sig {
+		}
+	}()
+	return e.out, nil
+}
+
+// serveGateway runs a linkage of e with the http gateway, it returns the gateway url
+func serveGateway(t *testing.T, e Engine, opts ...Option) string {
+	addr := freeAddr(t)
+	httpAddr := freeAddr(t)
+	opts = append(opts, WithHTTPGateway(httpAddr, nil))
+	l, err := InitLinkage(addr, e, nil, func(code Code) bool { return code == "yo" }, nil, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Run()
+	t.Cleanup(func() { l.Stop() })
+	waitListening(t, httpAddr)
+	return "http://" + httpAddr
+}
+
+func postJobs(t *testing.T, url string, code string, body string) (int, *submitResponse) {
+	req, err := http.NewRequest(http.MethodPost, url+"/jobs", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(HeaderCode, code)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var sr submitResponse
+	json.NewDecoder(resp.Body).Decode(&sr)
+	return resp.StatusCode, &sr
+}
+
+func TestGatewaySubmit(t *testing.T) {
+	e := newCollectEngine()
+	url := serveGateway(t, e)
+
+	resp, err := http.Get(url + "/jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("get got status %v", resp.StatusCode)
+	}
+
+	cases := []struct {
+		name     string
+		code     string
+		body     string
+		status   int
+		receipts int
+	}{
+		{"wrong code", "no", `{"payload":"a"}`, http.StatusUnauthorized, 0},
+		{"bad json", "yo", `{"payload":`, http.StatusBadRequest, 0},
+		{"one", "yo", `{"payload":"a"}`, http.StatusAccepted, 1},
+		{"array", "yo", `[{"payload":"b"},{"payload":"c"}]`, http.StatusAccepted, 2},
+		{"expired", "yo", `{"payload":"d","deadline":"2000-01-01T00:00:00Z"}`, http.StatusUnprocessableEntity, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, sr := postJobs(t, url, tc.code, tc.body)
+			if code != tc.status || len(sr.Receipts) != tc.receipts {
+				t.Fatalf("got status %v with %v receipts, want %v with %v", code, len(sr.Receipts), tc.status, tc.receipts)
+			}
+		})
+	}
+
+	for _, want := range []string{"a", "b", "c"} {
+		if j := e.wait(t, time.Second); j.Payload != want {
+			t.Fatalf("engine got %q, want %q", j.Payload, want)
+		}
+	}
+}
+
+func TestGatewayStream(t *testing.T) {
+	cases := []struct {
+		name   string
+		accept string
+		// line returns the json of the job from the line, or "" to skip it
+		line func(l string) string
+	}{
+		{"sse", "text/event-stream", func(l string) string {
+			return strings.TrimPrefix(l, "data: ")
+		}},
+		{"json lines", "", func(l string) string {
+			return l
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := serveGateway(t, newRelayEngine())
+
+			req, err := http.NewRequest(http.MethodGet, url+"/jobs/stream?code=yo", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", tc.accept)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("got status %v", resp.StatusCode)
+			}
+
+			_, sr := postJobs(t, url, "yo", `{"payload":"a"}`)
+			if len(sr.Receipts) != 1 || !sr.Receipts[0].Accepted {
+				t.Fatalf("got receipts %+v", sr.Receipts)
+			}
+
+			got := make(chan *Job, 1)
+			go func() {
+				sc := bufio.NewScanner(resp.Body)
+				for sc.Scan() {
+					var j Job
+					if json.Unmarshal([]byte(tc.line(sc.Text())), &j) == nil {
+						got <- &j
+						return
+					}
+				}
+			}()
+			select {
+			case j := <-got:
+				if j.ID != sr.Receipts[0].ID || j.Payload != "a" {
+					t.Fatalf("got job %+v, want %v", j, sr.Receipts[0].ID)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("no job streamed")
+			}
+		})
+	}
+}
+
+func TestGatewayStreamAuth(t *testing.T) {
+	url := serveGateway(t, newRelayEngine())
+	resp, err := http.Get(url + "/jobs/stream?code=no")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("got status %v, want unauthorized", resp.StatusCode)
+	}
+}
+
+func TestGatewayOrigin(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []string
+		origin  func(url string) string
+		status  int
+	}{
+		{"no origin", nil, func(string) string { return "" }, http.StatusAccepted},
+		{"same origin", nil, func(url string) string { return url }, http.StatusAccepted},
+		{"other origin", nil, func(string) string { return "https://evil.example.com" }, http.StatusForbidden},
+		{"allowed origin", []string{"https://dash.example.com"}, func(string) string { return "https://dash.example.com" }, http.StatusAccepted},
+		{"any origin", []string{"*"}, func(string) string { return "https://evil.example.com" }, http.StatusAccepted},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := serveGateway(t, newCollectEngine(), WithGatewayOrigins(tc.allowed...))
+			req, err := http.NewRequest(http.MethodPost, url+"/jobs", strings.NewReader(`{"payload":"a"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(HeaderCode, "yo")
+			if origin := tc.origin(url); origin != "" {
+				req.Header.Set("Origin", origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("got status %v, want %v", resp.StatusCode, tc.status)
+			}
+		})
+	}
+}
@@ -1,20 +1,84 @@
 package linkage
 
-import "linkage/proto/job"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"linkage/proto/job"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MetaSource is the metadata key of the upstream address a job recieved from
+const MetaSource = "linkage_source"
 
 // Job struct
 // code is for dispatcher know what kind of worker response for this job
+// routing key is matched with topics subscribed by downstreams
+// jobs with higher priority are sent before others waiting in linkage
+// jobs past the deadline are not sent, it never expires if Deadline is zero
+// Data is the binary payload used instead of Payload if it is set,
+// ContentType and Encoding describe it.
+// Trace is the span the job is in, it is sent as MetaTraceParent in metadata
 type Job struct {
-	Payload  string            `json:"payload"`
-	Metadata map[string]string `json:"metadata"`
+	ID          string            `json:"id"`
+	RoutingKey  string            `json:"routing_key"`
+	Priority    int32             `json:"priority"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Deadline    time.Time         `json:"deadline"`
+	Payload     string            `json:"payload"`
+	Data        []byte            `json:"data,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Encoding    string            `json:"encoding,omitempty"`
+	Metadata    map[string]string `json:"metadata"`
+	Trace       SpanContext       `json:"trace"`
+
+	done     func()
+	reject   func(err error)
+	taken    func()
+	attempts int
 }
 
 // CreateJob creates a job and the created time
 func CreateJob(payload string, metadata map[string]string) *Job {
 	return &Job{
-		Payload:  payload,
-		Metadata: metadata,
+		ID:        newJobID(),
+		CreatedAt: time.Now(),
+		Payload:   payload,
+		Metadata:  metadata,
+	}
+}
+
+// CreateBytesJob creates a job with binary payload and the created time
+func CreateBytesJob(data []byte, contentType string, metadata map[string]string) *Job {
+	return &Job{
+		ID:          newJobID(),
+		CreatedAt:   time.Now(),
+		Data:        data,
+		ContentType: contentType,
+		Metadata:    metadata,
+	}
+}
+
+// ExpiredHandler takes the jobs past the deadline instead of dropping them
+type ExpiredHandler = func(j *Job)
+
+// Expired returns true if the job is past the deadline
+func (j *Job) Expired() bool {
+	if j == nil || j.Deadline.IsZero() {
+		return false
 	}
+
+	return time.Now().After(j.Deadline)
+}
+
+// GetID is nil-safe method to get id in job
+func (j *Job) GetID() string {
+	if j == nil {
+		return ""
+	}
+
+	return j.ID
 }
 
 // GetPayload is nil-safe method to get payload in job
@@ -35,16 +99,111 @@ func (j *Job) GetMetadata() map[string]string {
 	return j.Metadata
 }
 
+// Done tells linkage the engine finished the job,
+// it is nil-safe and does nothing if linkage doesn't track the job
+func (j *Job) Done() {
+	if j == nil || j.done == nil {
+		return
+	}
+
+	j.done()
+}
+
+// Reject tells linkage the engine can't handle the job,
+// it is sent to dead letter sink if linkage has one.
+// it is nil-safe and does nothing if linkage doesn't track the job
+func (j *Job) Reject(err error) {
+	if j == nil || j.reject == nil {
+		return
+	}
+
+	j.reject(err)
+}
+
+// newJobID returns a random 16 bytes hex string
+func newJobID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
 func toGRPCJob(j *Job) *job.Job {
 	return &job.Job{
-		Payload:  j.Payload,
-		Metadata: j.Metadata,
+		Id:          j.ID,
+		RoutingKey:  j.RoutingKey,
+		Priority:    j.Priority,
+		CreatedAt:   unixNano(j.CreatedAt),
+		Deadline:    unixNano(j.Deadline),
+		Payload:     j.Payload,
+		Data:        j.Data,
+		ContentType: j.ContentType,
+		Encoding:    j.Encoding,
+		Metadata:    withTraceParent(j.Metadata, j.Trace),
 	}
 }
 
 func toLinkageJob(j *job.Job) *Job {
+	md, trace := takeTraceParent(j.GetMetadata())
 	return &Job{
-		Payload:  j.GetPayload(),
-		Metadata: j.GetMetadata(),
+		ID:          j.GetId(),
+		RoutingKey:  j.GetRoutingKey(),
+		Priority:    j.GetPriority(),
+		CreatedAt:   fromUnixNano(j.GetCreatedAt()),
+		Deadline:    fromUnixNano(j.GetDeadline()),
+		Payload:     j.GetPayload(),
+		Data:        j.GetData(),
+		ContentType: j.GetContentType(),
+		Encoding:    j.GetEncoding(),
+		Metadata:    md,
+		Trace:       trace,
+	}
+}
+
+// withTraceParent returns a copy of metadata with the traceparent of trace
+func withTraceParent(md map[string]string, trace SpanContext) map[string]string {
+	if !trace.IsValid() {
+		return md
+	}
+
+	cp := make(map[string]string, len(md)+1)
+	for k, v := range md {
+		cp[k] = v
+	}
+	cp[MetaTraceParent] = trace.String()
+	return cp
+}
+
+// takeTraceParent removes the traceparent from metadata and parses it
+func takeTraceParent(md map[string]string) (map[string]string, SpanContext) {
+	tp, ok := md[MetaTraceParent]
+	if !ok {
+		return md, SpanContext{}
+	}
+	delete(md, MetaTraceParent)
+
+	trace, err := ParseTraceParent(tp)
+	if err != nil {
+		log.Warnf("ignore invalid traceparent %q", tp)
+	}
+	return md, trace
+}
+
+// unixNano returns 0 for zero time
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano returns zero time for 0
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
 	}
+	return time.Unix(0, n)
 }
@@ -0,0 +1,109 @@
+package linkage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ctxEngine is an EngineV2 tells when its contexts are canceled
+type ctxEngine struct {
+	stopped  chan struct{}
+	infos    chan RegisterInfo
+	streamed chan struct{}
+}
+
+func newCtxEngine() *ctxEngine {
+	return &ctxEngine{
+		stopped:  make(chan struct{}),
+		infos:    make(chan RegisterInfo, 1),
+		streamed: make(chan struct{}),
+	}
+}
+
+func (e *ctxEngine) Start(ctx context.Context, in <-chan *Job) error {
+	<-ctx.Done()
+	close(e.stopped)
+	return nil
+}
+
+func (e *ctxEngine) Register(ctx context.Context, info RegisterInfo) (<-chan *Job, error) {
+	e.infos <- info
+	go func() {
+		<-ctx.Done()
+		close(e.streamed)
+	}()
+	return make(chan *Job), nil
+}
+
+func TestRunContext(t *testing.T) {
+	e := newCtxEngine()
+	l, err := InitLinkage(freeAddr(t), nil, nil, func(Code) bool { return true }, nil, nil,
+		WithEngineV2(e), WithDrainTimeouts(DrainTimeouts{Shutdown: time.Second}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- l.RunContext(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Fatalf("got error %v, want context canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("linkage not stopped by the context")
+	}
+	select {
+	case <-e.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("engine context not canceled")
+	}
+}
+
+func TestEngineV2Register(t *testing.T) {
+	e := newCtxEngine()
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:       addr,
+		EngineV2:   e,
+		CodeAssert: func(Code) bool { return true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	c := dialTest(t, &DialInfo{
+		Addr:   addr,
+		Opts:   []grpc.DialOption{grpc.WithInsecure()},
+		Topics: []string{"orders.#"},
+	})
+
+	select {
+	case info := <-e.infos:
+		if len(info.Topics) != 1 || info.Topics[0] != "orders.#" || info.Peer == "" ||
+			info.Identity == nil || info.Signal == nil {
+			t.Fatalf("got register info %+v", info)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("engine not registered")
+	}
+
+	// the context of the registration ends with the stream
+	c.Close()
+	select {
+	case <-e.streamed:
+	case <-time.After(time.Second):
+		t.Fatal("register context not canceled")
+	}
+}
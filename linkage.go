@@ -1,7 +1,11 @@
 package linkage
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"io"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -9,107 +13,219 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// Linkage is the main service to require jobs
+// Linkage is the main service to require jobs from upstreams
 // and send jobs to connected client.
 // It also an Engine
 type Linkage struct {
 	server       *Server
-	client       *Client
-	engine       Engine
+	clients      []*Client
+	engine       EngineV2
 	income       chan *Job
-	waiting      Waiting
+	pending      *jobQueue
+	slots        chan struct{}
+	buffer       int
+	backoff      func() Waiting
+	store        JobStore
+	auth         Authenticator
+	expired      ExpiredHandler
+	deadLetter   DeadLetterSink
+	maxAttempts  int
+	compressions []string
+	minCompress  int
+	maxJobSize   int
+	maxSubmit    int
+	metrics      Metrics
+	exporter     SpanExporter
+	admin        *adminServer
+	gatewayAddr  Addr
+	gatewayTLS   *tls.Config
+	origins      []string
+	gateway      *gateway
+	drain        DrainTimeouts
+	draining     chan struct{}
+	drainMu      sync.Mutex
+	receiving    sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+	closing      chan struct{}
+	stopOnce     sync.Once
 	closeCh      chan struct{}
 	serverDoneCh chan struct{}
 }
 
-// InitLinkage init a linkage service
-func InitLinkage(addr Addr, engine Engine, srvOpts []grpc.ServerOption, codeAssert CodeAssert, di *DialInfo, w Waiting) (*Linkage, error) {
+// defaultIncomeBuffer is the max number of jobs waiting for engine
+const defaultIncomeBuffer = 64
+
+var (
+	errClosing = errors.New("linkage is closing")
+	errExpired = errors.New("job expired")
+)
+
+// InitLinkage init a linkage service.
+// The waiting function w is deprecated and ignored, pass nil:
+// each upstream waits by its own backoff between attempts to reconnect, see WithBackoff
+func InitLinkage(addr Addr, engine Engine, srvOpts []grpc.ServerOption, codeAssert CodeAssert, dis []*DialInfo, w Waiting, opts ...Option) (*Linkage, error) {
 
 	// TODO: check parameter
 
-	if w == nil {
-		log.Info("use defult wait to retry mechanism")
-		start := 1
-		grow := 2
-		maxAttempt := 3
-		w = WaitFactory(start, grow, maxAttempt)
+	if w != nil {
+		log.Warn("waiting function is deprecated and not used, use WithBackoff instead")
 	}
 
 	l := &Linkage{
-		server:  nil,
-		client:  nil,
-		engine:  engine,
-		income:  make(chan *Job),
-		waiting: w,
-		closeCh: make(chan struct{}),
+		server:   nil,
+		clients:  nil,
+		income:   make(chan *Job),
+		pending:  newJobQueue(),
+		buffer:   defaultIncomeBuffer,
+		metrics:  nopMetrics{},
+		exporter: nopExporter{},
+		drain:    DefaultDrainTimeouts,
+		draining: make(chan struct{}),
+		closing:  make(chan struct{}),
+		closeCh:  make(chan struct{}),
+	}
+	if engine != nil {
+		l.engine = AdaptEngine(engine)
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.engine == nil {
+		return nil, errors.New("no engine")
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	if l.buffer < 1 {
+		l.buffer = 1
+	}
+	l.slots = make(chan struct{}, l.buffer)
+	l.drain = l.drain.withDefaults()
 
-	if di != nil {
-		log.Infof("initial client")
+	for _, di := range dis {
+		log.Infof("initial client of %v", di.Addr)
 		cli, err := InitClient(di)
 		if err != nil {
 			log.Errorf("fail to initial client")
 			return nil, err
 		}
 		log.Infof("initial client success")
-		l.client = cli
+		cli.metrics = l.metrics
+		cli.quit = l.draining
+		if l.backoff != nil {
+			cli.backoff = l.backoff
+		}
+		l.clients = append(l.clients, cli)
 	}
 
 	srvCfg := &ServerConfig{
-		Addr:       addr,
-		Engine:     l,
-		SrvOpts:    srvOpts,
-		CodeAssert: codeAssert,
+		Addr:            addr,
+		EngineV2:        l.engine,
+		SrvOpts:         srvOpts,
+		CodeAssert:      codeAssert,
+		Auth:            l.auth,
+		Expired:         l.expired,
+		DeadLetter:      l.deadLetter,
+		MaxAttempts:     l.maxAttempts,
+		Compressions:    l.compressions,
+		MinCompressSize: l.minCompress,
+		MaxJobSize:      l.maxJobSize,
+		MaxSubmitJobs:   l.maxSubmit,
+		Metrics:         l.metrics,
+		Exporter:        l.exporter,
+		DrainTimeout:    l.drain.Downstream,
 	}
 	srv, err := InitServer(srvCfg)
 	if err != nil {
 		return nil, err
 	}
 	log.Printf("init server")
+	if l.admin != nil {
+		srv.admin = l.admin
+	}
+	srv.submit = l.submit
+	if l.gatewayAddr != "" {
+		l.gateway = newGateway(l.gatewayAddr, l.gatewayTLS, srv, l.origins)
+	}
 	l.server = srv
-	l.engine = engine
 	return l, nil
 }
 
 // Run start to run linkage service
 func (s *Linkage) Run() error {
-	// start client
-	if s.client != nil {
-		err := s.client.BuildStream()
-		if err != nil {
-			st := status.Convert(err)
-			log.WithFields(log.Fields{
-				"error_code":    st.Code(),
-				"error_message": st.Message(),
-			}).Error("fail to build stream")
-			return err
-		}
-		go s.askJobRoutine()
+	return s.RunContext(context.Background())
+}
+
+// RunContext runs linkage until ctx is canceled or Stop is called,
+// linkage drains like Stop when ctx is canceled, then ctx.Err() is returned.
+// Clients, engine and server run with the context of linkage,
+// it is canceled after linkage drained
+func (s *Linkage) RunContext(ctx context.Context) error {
+	// jobs left in store from last run
+	unfinished, err := s.unfinished()
+	if err != nil {
+		log.Errorf("fail to load unfinished jobs, error: %v", err)
+		return err
 	}
 
 	// start engine
+	go s.pump()
 	go func() {
-		err := s.engine.Start(s.income)
+		err := s.engine.Start(s.ctx, s.income)
 		if err != nil {
+			log.Errorf("engine stopped, error: %v", err)
 			s.Stop()
 		}
 	}()
 
+	// replay unfinished jobs before asking new ones,
+	// upstreams not reachable yet are reconnected like broken ones
+	go func() {
+		s.replay(unfinished)
+		for _, cli := range s.clients {
+			go s.askJobRoutine(cli)
+		}
+	}()
+
 	// start server
 	go func() {
-		err := s.server.Run()
-		if err != nil {
+		err := s.server.RunContext(s.ctx)
+		if err != nil && s.ctx.Err() == nil {
 			s.Stop()
 		}
 	}()
 
-	<-s.closeCh
-	return nil
+	if s.gateway != nil {
+		go func() {
+			err := s.gateway.run()
+			if err != nil {
+				log.Errorf("http gateway stopped, error: %v", err)
+				s.Stop()
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		s.Stop()
+		return ctx.Err()
+	case <-s.closeCh:
+		return nil
+	}
 }
 
 // Register interface
 func (s *Linkage) Register(sig chan Signal) (<-chan *Job, error) {
-	return s.engine.Register(sig)
+	return s.engine.Register(s.ctx, RegisterInfo{
+		Signal: sig,
+	})
+}
+
+// RegisterIdentity interface
+func (s *Linkage) RegisterIdentity(id *Identity, sig chan Signal) (<-chan *Job, error) {
+	return s.engine.Register(s.ctx, RegisterInfo{
+		Identity: id,
+		Signal:   sig,
+	})
 }
 
 // Start interface
@@ -118,39 +234,91 @@ func (s *Linkage) Start(<-chan *Job) error {
 }
 
 // Stop interface
+// it drains linkage: stops asking jobs from upstreams, waits for engine
+// to take the queued jobs and acks them to upstreams, flushes streams to downstreams, then closes.
+// Each phase is bounded by DrainTimeouts, see WithDrainTimeouts
 func (s *Linkage) Stop() error {
-	s.client.Close()
+	s.stopOnce.Do(func() {
+		log.Info("drain linkage")
+		s.stopUpstreams(s.drain.Upstream)
+		s.flushEngine(s.drain.Engine)
+		s.closeUpstreams()
+		close(s.closing)
 
-	done := s.server.Close()
-	timeThreshold := time.After(2 * time.Minute)
-	select {
-	case <-done:
-		log.Infof("server close gracefully")
-	case <-timeThreshold:
-		log.Infof("server close at timeup")
-	}
+		done := s.server.Close()
+		timeThreshold := time.After(s.drain.Shutdown)
+		select {
+		case <-done:
+			log.Infof("server close gracefully")
+		case <-timeThreshold:
+			log.Infof("server close at timeup")
+		}
+		if s.gateway != nil {
+			s.gateway.close(s.drain.Shutdown)
+		}
+		s.cancel()
+		s.closeSinks()
 
-	close(s.closeCh)
+		close(s.closeCh)
+	})
 	return nil
 }
 
-func (s *Linkage) askJobRoutine() {
+// closeSinks closes the job store and dead letter sink after streams are closed
+func (s *Linkage) closeSinks() {
+	if s.store != nil {
+		err := s.store.Close()
+		if err != nil {
+			log.Errorf("close job store fail, error: %v", err)
+		}
+	}
+	if s.deadLetter != nil {
+		err := s.deadLetter.Close()
+		if err != nil {
+			log.Errorf("close dead letter sink fail, error: %v", err)
+		}
+	}
+}
 
-	// TODO: rate limit
+// askJobRoutine builds the stream of the client and asks jobs until linkage is draining
+func (s *Linkage) askJobRoutine(cli *Client) {
+	logger := log.WithFields(log.Fields{
+		"address": cli.info.Addr,
+	})
 
+	err := cli.BuildStreamContext(s.ctx)
 	for {
-		err := s.askJob()
 		if err != nil {
-			log.Errorf("ask job fail, error: %v", err)
-			log.Info("stop linkage")
-			s.Stop()
+			if s.isDraining() {
+				return
+			}
+
+			// upstream may be down or restarting, keep server and engine running
+			st := status.Convert(err)
+			logger.WithFields(log.Fields{
+				"error_code":    st.Code(),
+				"error_message": st.Message(),
+			}).Error("stream broken")
+			logger.Info("reconnect to upstream")
+			err = cli.Reconnect()
+			if err != nil {
+				// only this upstream is given up, producers and other upstreams still feed the engine
+				logger.Errorf("give up upstream, error: %v", err)
+				return
+			}
+			logger.Info("reconnect success")
+		}
+
+		if s.isDraining() || !cli.waitResumed(s.draining) {
 			return
 		}
+		err = s.askJob(cli)
 	}
 }
 
-func (s *Linkage) askJob() error {
-	j, err := s.retry()
+func (s *Linkage) askJob(cli *Client) error {
+	// the stream is broken once Recv fails, so reconnect instead of receiving again
+	j, err := cli.Ask()
 	if err != nil {
 		if err == io.EOF {
 			return io.EOF
@@ -160,31 +328,232 @@ func (s *Linkage) askJob() error {
 		log.WithFields(log.Fields{
 			"error_code":    st.Code(),
 			"error_message": st.Message(),
-			"address":       s.client.info.Addr,
+			"address":       cli.info.Addr,
 		}).Error("recieve fail, close connect")
 		return st.Err()
 	}
 
-	s.income <- j
+	// the job comes after draining started, upstream redelivers it
+	if !s.beginReceive() {
+		return cli.Nack(j.ID, errClosing.Error())
+	}
+	defer s.endReceive()
+
+	s.metrics.JobReceived(cli.info.Addr)
+	span := startSpan("linkage.receive", j.Trace, map[string]string{
+		"job.id":   j.ID,
+		"upstream": cli.info.Addr,
+	})
+	j.Trace = span.Context
+	err = s.receive(cli, j)
+	span.end(s.exporter, err)
+	return err
+}
+
+// receive hands the job from upstream to engine, it is acked once engine takes it,
+// so upstream redelivers the job still queued in linkage if linkage crashes
+func (s *Linkage) receive(cli *Client, j *Job) error {
+	id := j.ID
+	j.taken = func() {
+		s.ack(cli, id)
+	}
+
+	err := s.take(j, cli.info.Addr)
+	switch {
+	case err == errExpired:
+		// the expired job is handled by dropping it, no need to redeliver
+		return s.ack(cli, id)
+	case err != nil:
+		return cli.Nack(id, err.Error())
+	}
+	return nil
+}
+
+// ack tells upstream the job is delivered
+func (s *Linkage) ack(cli *Client, id string) error {
+	err := cli.Ack(id)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"id":      id,
+			"address": cli.info.Addr,
+		}).Errorf("ack fail, error: %v", err)
+	}
+	return err
+}
+
+// take marks the source of the job and feeds it,
+// the job expired is dropped or diverted to expired handler
+func (s *Linkage) take(j *Job, source string) error {
+	if j.Metadata == nil {
+		j.Metadata = make(map[string]string)
+	}
+	j.Metadata[MetaSource] = source
+
+	if j.Expired() {
+		s.expire(j)
+		return errExpired
+	}
+
+	err := s.feed(j)
+	if err != nil && err != errClosing {
+		log.WithFields(log.Fields{
+			"id": j.ID,
+		}).Errorf("store job fail, error: %v", err)
+		s.metrics.JobDropped("store")
+	}
+	return err
+}
+
+// feed keeps the job in store then queues it for engine.
+// The job failed to queue is marked done in store, since the upstream
+// or producer gets the error and sends it again
+func (s *Linkage) feed(j *Job) error {
+	s.rejectable(j)
+	if s.store != nil {
+		if s.isClosing() {
+			return errClosing
+		}
+		if j.ID == "" {
+			j.ID = newJobID()
+		}
+
+		err := s.store.Append(j)
+		if err != nil {
+			return err
+		}
+		s.track(j)
+	}
+
+	err := s.enqueue(j)
+	if err != nil {
+		j.Done()
+	}
+	return err
+}
+
+func (s *Linkage) isClosing() bool {
+	select {
+	case <-s.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue waits for a free slot and queues the job for engine
+func (s *Linkage) enqueue(j *Job) error {
+	select {
+	case s.slots <- struct{}{}:
+	case <-s.closing:
+		return errClosing
+	}
+
+	s.pending.push(j)
 	return nil
 }
 
-func (s *Linkage) retry() (*Job, error) {
+// pump sends queued jobs to engine, the one with highest priority first
+func (s *Linkage) pump() {
 	for {
-		gj, err := s.client.Ask()
-		if err == nil {
-			return gj, nil
+		select {
+		case <-s.pending.ready:
+		case <-s.closing:
+			return
 		}
 
-		if err == io.EOF {
-			return nil, err
+		for j := s.pending.pop(); j != nil; j = s.pending.pop() {
+			// engine owns the job once taken, so keep the hook before
+			taken := j.taken
+			if j.Expired() {
+				s.expire(j)
+				j.Done()
+				handed(taken)
+				<-s.slots
+				continue
+			}
+
+			span := startSpan("linkage.handoff", j.Trace, map[string]string{
+				"job.id": j.ID,
+			})
+			j.Trace = span.Context
+
+			select {
+			case s.income <- j:
+				handed(taken)
+				<-s.slots
+				span.end(s.exporter, nil)
+			case <-s.closing:
+				span.end(s.exporter, errClosing)
+				return
+			}
 		}
+	}
+}
+
+// handed calls taken of the job handed to engine if it is set
+func handed(taken func()) {
+	if taken != nil {
+		taken()
+	}
+}
 
-		log.Errorf("recieve fail, error: %v", err)
-		log.Info("wait to retry")
-		err = s.waiting()
+// rejectable sets reject of the job to send it to dead letter sink
+func (s *Linkage) rejectable(j *Job) {
+	j.reject = func(err error) {
+		lastErr := ""
 		if err != nil {
-			return nil, err
+			lastErr = err.Error()
+		}
+
+		putDeadLetter(s.deadLetter, s.metrics, j, "rejected", lastErr)
+		j.Done()
+	}
+}
+
+// expire drops the job past the deadline or diverts it to expired handler
+func (s *Linkage) expire(j *Job) {
+	log.WithFields(log.Fields{
+		"id":       j.ID,
+		"deadline": j.Deadline,
+	}).Warn("job expired")
+	s.metrics.JobDropped("expired")
+	if s.expired != nil {
+		s.expired(j)
+	}
+}
+
+// track sets done of the job to mark it done in store
+func (s *Linkage) track(j *Job) {
+	id := j.ID
+	j.done = func() {
+		err := s.store.Done(id)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id": id,
+			}).Errorf("mark job done fail, error: %v", err)
+		}
+	}
+}
+
+func (s *Linkage) unfinished() ([]*Job, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+
+	return s.store.Unfinished()
+}
+
+func (s *Linkage) replay(jobs []*Job) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	log.Infof("replay %v unfinished jobs", len(jobs))
+	for _, j := range jobs {
+		s.rejectable(j)
+		s.track(j)
+		if s.enqueue(j) != nil {
+			return
 		}
 	}
 }
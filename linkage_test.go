@@ -0,0 +1,320 @@
+package linkage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// queueEngine sends the jobs pushed to it to any downstream
+type queueEngine struct {
+	jobs chan *Job
+}
+
+func newQueueEngine() *queueEngine {
+	return &queueEngine{
+		jobs: make(chan *Job, 16),
+	}
+}
+
+func (e *queueEngine) Start(<-chan *Job) error { return nil }
+
+func (e *queueEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	go func() {
+		for range sig {
+		}
+	}()
+	return e.jobs, nil
+}
+
+// collectEngine takes the jobs from upstreams, it sends nothing to downstreams
+type collectEngine struct {
+	got chan *Job
+}
+
+func newCollectEngine() *collectEngine {
+	return &collectEngine{
+		got: make(chan *Job, 16),
+	}
+}
+
+func (e *collectEngine) Start(in <-chan *Job) error {
+	for j := range in {
+		e.got <- j
+	}
+	return nil
+}
+
+func (e *collectEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	return make(chan *Job), nil
+}
+
+// wait returns the next job taken, it fails if no job comes before timeout
+func (e *collectEngine) wait(t *testing.T, timeout time.Duration) *Job {
+	select {
+	case j := <-e.got:
+		return j
+	case <-time.After(timeout):
+		t.Fatal("no job taken")
+		return nil
+	}
+}
+
+// shortWait waits a moment between retries and fails every n calls
+func shortWait(n int) Waiting {
+	calls := 0
+	return func(ctx context.Context) error {
+		calls++
+		if calls%n == 0 {
+			return errors.New("retry later")
+		}
+		return sleep(ctx, 20*time.Millisecond)
+	}
+}
+
+// serveUpstream runs a server of e on a free port and returns its address
+func serveUpstream(t *testing.T, e Engine) string {
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:       addr,
+		Engine:     e,
+		CodeAssert: func(Code) bool { return true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	t.Cleanup(func() { <-srv.Close() })
+	waitListening(t, addr)
+	return addr
+}
+
+// waitListening waits until the server at addr accepts connections
+func waitListening(t *testing.T, addr string) {
+	for i := 0; i < 50; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err == nil {
+			c.Close()
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%v is not listening", addr)
+}
+
+// testProxy forwards connections to target, stop drops them like the target restarts
+type testProxy struct {
+	addr   string
+	target string
+	mu     sync.Mutex
+	l      net.Listener
+	conns  []net.Conn
+}
+
+func startProxy(t *testing.T, target string) *testProxy {
+	p := &testProxy{
+		addr:   freeAddr(t),
+		target: target,
+	}
+	p.start(t)
+	t.Cleanup(p.stop)
+	return p
+}
+
+func (p *testProxy) start(t *testing.T) {
+	l, err := net.Listen("tcp", p.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.l = l
+	p.mu.Unlock()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			u, err := net.Dial("tcp", p.target)
+			if err != nil {
+				c.Close()
+				continue
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, c, u)
+			p.mu.Unlock()
+			go io.Copy(c, u)
+			go io.Copy(u, c)
+		}
+	}()
+}
+
+func (p *testProxy) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.l.Close()
+	for _, c := range p.conns {
+		c.Close()
+	}
+	p.conns = nil
+}
+
+func TestLinkageReconnect(t *testing.T) {
+	up := newQueueEngine()
+	proxy := startProxy(t, serveUpstream(t, up))
+
+	e := newCollectEngine()
+	l, err := InitLinkage(freeAddr(t), e, nil, func(Code) bool { return true }, []*DialInfo{{
+		Addr:       proxy.addr,
+		Opts:       []grpc.DialOption{grpc.WithInsecure()},
+		MaxAttempt: 5,
+	}}, shortWait(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Run()
+	defer l.Stop()
+
+	up.jobs <- CreateJob("before", nil)
+	if j := e.wait(t, 5*time.Second); j.Payload != "before" {
+		t.Fatalf("got %q, want before", j.Payload)
+	}
+
+	// the upstream restarts, linkage keeps running and reconnects
+	proxy.stop()
+	time.Sleep(100 * time.Millisecond)
+	proxy.start(t)
+
+	// the job not acked before the restart may come again
+	up.jobs <- CreateJob("after", nil)
+	for j := e.wait(t, 10*time.Second); j.Payload != "after"; j = e.wait(t, 10*time.Second) {
+	}
+}
+
+func TestLinkageUpstreamLate(t *testing.T) {
+	up := newQueueEngine()
+	proxy := startProxy(t, serveUpstream(t, up))
+	proxy.stop()
+
+	e := newCollectEngine()
+	l, err := InitLinkage(freeAddr(t), e, nil, func(Code) bool { return true }, []*DialInfo{{
+		Addr: proxy.addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	}}, nil, WithBackoff(func() Waiting { return shortWait(100) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := make(chan error, 1)
+	go func() { ran <- l.Run() }()
+	defer l.Stop()
+
+	// the upstream not reachable at start is reconnected
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case err := <-ran:
+		t.Fatalf("linkage stopped, error: %v", err)
+	default:
+	}
+	proxy.start(t)
+
+	up.jobs <- CreateJob("late", nil)
+	if j := e.wait(t, 5*time.Second); j.Payload != "late" {
+		t.Fatalf("got %q, want late", j.Payload)
+	}
+}
+
+func TestReconnectStops(t *testing.T) {
+	cases := []struct {
+		name string
+		want error
+	}{
+		{"canceled", context.Canceled},
+		{"quit", errQuit},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cli, err := InitClient(&DialInfo{
+				Addr: freeAddr(t),
+				Opts: []grpc.DialOption{grpc.WithInsecure()},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			quit := make(chan struct{})
+			cli.quit = quit
+			cli.backoff = func() Waiting { return Backoff(time.Hour, time.Hour) }
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if cli.BuildStreamContext(ctx) == nil {
+				t.Fatal("stream built without upstream")
+			}
+			done := make(chan error, 1)
+			go func() { done <- cli.Reconnect() }()
+
+			// the client waiting to reconnect stops at once
+			time.Sleep(100 * time.Millisecond)
+			if tc.want == errQuit {
+				close(quit)
+			} else {
+				cancel()
+			}
+			select {
+			case err := <-done:
+				if err != tc.want {
+					t.Fatalf("got error %v, want %v", err, tc.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("client still reconnecting")
+			}
+			if state, _ := cli.State(); state != StateClosed {
+				t.Fatalf("client is %v, want %v", state, StateClosed)
+			}
+		})
+	}
+}
+
+func TestLinkageFanIn(t *testing.T) {
+	ups := []*queueEngine{newQueueEngine(), newQueueEngine()}
+	var dis []*DialInfo
+	for _, up := range ups {
+		dis = append(dis, &DialInfo{
+			Addr: serveUpstream(t, up),
+			Opts: []grpc.DialOption{grpc.WithInsecure()},
+		})
+	}
+
+	e := newCollectEngine()
+	l, err := InitLinkage(freeAddr(t), e, nil, func(Code) bool { return true }, dis, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Run()
+	defer l.Stop()
+
+	for i, up := range ups {
+		up.jobs <- CreateJob(dis[i].Addr, nil)
+	}
+
+	// each job is tagged with the upstream it comes from
+	got := map[string]bool{}
+	for range ups {
+		j := e.wait(t, 5*time.Second)
+		if src := j.Metadata[MetaSource]; src != j.Payload {
+			t.Errorf("source of job from %v is %q", j.Payload, src)
+		}
+		got[j.Payload] = true
+	}
+	for _, di := range dis {
+		if !got[di.Addr] {
+			t.Errorf("no job from %v", di.Addr)
+		}
+	}
+}
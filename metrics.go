@@ -0,0 +1,56 @@
+package linkage
+
+import (
+	"net"
+	"time"
+)
+
+// Metrics records what happens in linkage, see package metrics for prometheus
+type Metrics interface {
+	// JobReceived is called when a job is recieved from upstream
+	JobReceived(upstream Addr)
+	// JobSent is called when a job is sent to downstream
+	JobSent(peer string, latency time.Duration)
+	// JobDropped is called when a job is refused or thrown away
+	JobDropped(reason string)
+	// StreamOpened is called when a downstream Ask stream is opened
+	StreamOpened(peer string)
+	// StreamClosed is called when a downstream Ask stream is closed
+	StreamClosed(peer string)
+	// ReconnectAttempt is called when try to rebuild the stream to upstream
+	ReconnectAttempt(upstream Addr)
+	// RetryWaited is called after waiting to reconnect to upstream
+	RetryWaited(d time.Duration)
+	// SignalDropped is called when a signal to engine is thrown away,
+	// the engine does not take signals as fast as they come
+	SignalDropped(signal string)
+}
+
+// nopMetrics is the default Metrics which records nothing
+type nopMetrics struct{}
+
+func (nopMetrics) JobReceived(Addr)              {}
+func (nopMetrics) JobSent(string, time.Duration) {}
+func (nopMetrics) JobDropped(string)             {}
+func (nopMetrics) StreamOpened(string)           {}
+func (nopMetrics) StreamClosed(string)           {}
+func (nopMetrics) ReconnectAttempt(Addr)         {}
+func (nopMetrics) RetryWaited(time.Duration)     {}
+func (nopMetrics) SignalDropped(string)          {}
+
+func metricsOrNop(m Metrics) Metrics {
+	if m == nil {
+		return nopMetrics{}
+	}
+	return m
+}
+
+// peerHost strips the port of peer address,
+// the port of downstream changes on every connection
+func peerHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
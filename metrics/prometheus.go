@@ -0,0 +1,110 @@
+// Package metrics exposes linkage metrics in prometheus text format
+package metrics
+
+import (
+	"bytes"
+	"linkage"
+	"net/http"
+	"time"
+)
+
+var (
+	latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	waitBuckets    = []float64{1, 2, 4, 8, 16, 32, 64, 128}
+)
+
+// Prometheus implements linkage.Metrics and serves them for prometheus
+type Prometheus struct {
+	families []*family
+
+	received   *family
+	sent       *family
+	dropped    *family
+	latency    *family
+	streams    *family
+	reconnects *family
+	waits      *family
+	signals    *family
+}
+
+// InitPrometheus returns a Prometheus, all metric names start with namespace
+func InitPrometheus(namespace string) *Prometheus {
+	name := func(n string) string {
+		if namespace == "" {
+			return n
+		}
+		return namespace + "_" + n
+	}
+
+	p := &Prometheus{
+		received:   newFamily(name("jobs_received_total"), "Jobs recieved from upstream.", counter, nil, "upstream"),
+		sent:       newFamily(name("jobs_sent_total"), "Jobs sent to downstream.", counter, nil, "peer"),
+		dropped:    newFamily(name("jobs_dropped_total"), "Jobs refused or thrown away.", counter, nil, "reason"),
+		latency:    newFamily(name("job_send_seconds"), "Time to send a job to downstream.", histogram, latencyBuckets),
+		streams:    newFamily(name("ask_streams"), "Active Ask streams from downstream.", gauge, nil, "peer"),
+		reconnects: newFamily(name("reconnect_attempts_total"), "Attempts to rebuild stream to upstream.", counter, nil, "upstream"),
+		waits:      newFamily(name("retry_wait_seconds"), "Time waited before reconnecting to upstream.", histogram, waitBuckets),
+		signals:    newFamily(name("signals_dropped_total"), "Signals to engine thrown away.", counter, nil, "signal"),
+	}
+	p.families = []*family{p.received, p.sent, p.dropped, p.latency, p.streams, p.reconnects, p.waits, p.signals}
+	return p
+}
+
+// JobReceived implements linkage.Metrics
+func (p *Prometheus) JobReceived(upstream linkage.Addr) {
+	p.received.add(1, upstream)
+}
+
+// JobSent implements linkage.Metrics
+func (p *Prometheus) JobSent(peer string, latency time.Duration) {
+	p.sent.add(1, peer)
+	p.latency.observe(latency.Seconds())
+}
+
+// JobDropped implements linkage.Metrics
+func (p *Prometheus) JobDropped(reason string) {
+	p.dropped.add(1, reason)
+}
+
+// StreamOpened implements linkage.Metrics
+func (p *Prometheus) StreamOpened(peer string) {
+	p.streams.add(1, peer)
+}
+
+// StreamClosed implements linkage.Metrics
+func (p *Prometheus) StreamClosed(peer string) {
+	p.streams.add(-1, peer)
+}
+
+// ReconnectAttempt implements linkage.Metrics
+func (p *Prometheus) ReconnectAttempt(upstream linkage.Addr) {
+	p.reconnects.add(1, upstream)
+}
+
+// RetryWaited implements linkage.Metrics
+func (p *Prometheus) RetryWaited(d time.Duration) {
+	p.waits.observe(d.Seconds())
+}
+
+// SignalDropped implements linkage.Metrics
+func (p *Prometheus) SignalDropped(signal string) {
+	p.signals.add(1, signal)
+}
+
+// ServeHTTP writes all metrics in prometheus text format
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	for _, f := range p.families {
+		f.write(&buf)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+// Serve listens on addr and serves metrics on path
+func (p *Prometheus) Serve(addr string, path string) error {
+	mux := http.NewServeMux()
+	mux.Handle(path, p)
+	return http.ListenAndServe(addr, mux)
+}
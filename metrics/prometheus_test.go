@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := InitPrometheus("linkage")
+	p.JobReceived("up:1")
+	p.JobReceived("up:1")
+	p.JobSent("down", 30*time.Millisecond)
+	p.JobDropped("expired")
+	p.StreamOpened("down")
+	p.StreamOpened("down")
+	p.StreamClosed("down")
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	b, _ := ioutil.ReadAll(w.Result().Body)
+	out := string(b)
+
+	for _, line := range []string{
+		"# TYPE linkage_jobs_received_total counter",
+		`linkage_jobs_received_total{upstream="up:1"} 2`,
+		`linkage_jobs_sent_total{peer="down"} 1`,
+		`linkage_jobs_dropped_total{reason="expired"} 1`,
+		`linkage_ask_streams{peer="down"} 1`,
+		`linkage_job_send_seconds_bucket{le="0.025"} 0`,
+		`linkage_job_send_seconds_bucket{le="0.05"} 1`,
+		`linkage_job_send_seconds_bucket{le="+Inf"} 1`,
+		"linkage_job_send_seconds_count 1",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("line %q not found in:\n%v", line, out)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package linkage
+
+import "crypto/tls"
+
+// Option configures optional features of Linkage
+type Option func(l *Linkage)
+
+// WithJobStore keeps jobs in store before they are sent to engine,
+// jobs not done by engine are replayed when linkage runs again.
+// The store is closed when linkage stops
+func WithJobStore(store JobStore) Option {
+	return func(l *Linkage) {
+		l.store = store
+	}
+}
+
+// WithMetrics records metrics of linkage by m
+func WithMetrics(m Metrics) Option {
+	return func(l *Linkage) {
+		l.metrics = metricsOrNop(m)
+	}
+}
+
+// WithAuthenticator authenticates downstreams by auth instead of codeAssert
+func WithAuthenticator(auth Authenticator) Option {
+	return func(l *Linkage) {
+		l.auth = auth
+	}
+}
+
+// WithBackoff sets how upstreams wait between attempts to reconnect,
+// f is called for a new Waiting each time an upstream starts reconnecting,
+// the upstream gives up when the Waiting returns an error.
+// Upstreams wait a second at first and twice as long after each attempt up to a minute by default
+func WithBackoff(f func() Waiting) Option {
+	return func(l *Linkage) {
+		l.backoff = f
+	}
+}
+
+// WithIncomeBuffer sets the max number of jobs from upstreams waiting for engine,
+// jobs with higher priority in them are sent to engine first
+func WithIncomeBuffer(n int) Option {
+	return func(l *Linkage) {
+		l.buffer = n
+	}
+}
+
+// WithExpiredHandler diverts jobs past the deadline to h, they are dropped if it is not set
+func WithExpiredHandler(h ExpiredHandler) Option {
+	return func(l *Linkage) {
+		l.expired = h
+	}
+}
+
+// WithDeadLetter sends jobs rejected by engine to sink, and jobs to downstreams
+// failed maxAttempts times. Jobs are redelivered until they are acked if maxAttempts is less than 1.
+// The sink is closed when linkage stops
+func WithDeadLetter(sink DeadLetterSink, maxAttempts int) Option {
+	return func(l *Linkage) {
+		l.deadLetter = sink
+		l.maxAttempts = maxAttempts
+	}
+}
+
+// WithCompressions allows downstreams to receive jobs compressed by names,
+// jobs smaller than minSize bytes are not compressed, see ServerConfig
+func WithCompressions(minSize int, names ...string) Option {
+	return func(l *Linkage) {
+		l.compressions = names
+		l.minCompress = minSize
+	}
+}
+
+// WithMaxJobSize rejects jobs submitted compressed over n bytes after decompressed,
+// see ServerConfig. Set DialInfo.MaxJobSize for jobs from upstreams
+func WithMaxJobSize(n int) Option {
+	return func(l *Linkage) {
+		l.maxJobSize = n
+	}
+}
+
+// WithMaxSubmitJobs ends a Submit stream once n jobs are received, see ServerConfig
+func WithMaxSubmitJobs(n int) Option {
+	return func(l *Linkage) {
+		l.maxSubmit = n
+	}
+}
+
+// WithSpanExporter exports spans of receiving, handing off and sending jobs by e
+func WithSpanExporter(e SpanExporter) Option {
+	return func(l *Linkage) {
+		l.exporter = exporterOrNop(e)
+	}
+}
+
+// WithAdmin serves admin service alongside job service,
+// admin requests carry the passcode in MetaAdminCode metadata,
+// all requests are denied if codeAssert is nil
+func WithAdmin(codeAssert CodeAssert) Option {
+	return func(l *Linkage) {
+		l.admin = &adminServer{
+			l:          l,
+			codeAssert: codeAssert,
+		}
+	}
+}
+
+// WithHTTPGateway serves Submit and Ask over http and json at addr,
+// with TLS if tc is not nil. POST /jobs submits jobs and
+// GET /jobs/stream streams jobs as server-sent events or json lines
+func WithHTTPGateway(addr Addr, tc *tls.Config) Option {
+	return func(l *Linkage) {
+		l.gatewayAddr = addr
+		l.gatewayTLS = tc
+	}
+}
+
+// WithGatewayOrigins allows browsers of origins, such as "https://dash.example.com",
+// to call the http gateway. Only the origin of the gateway is allowed by default,
+// "*" allows any origin
+func WithGatewayOrigins(origins ...string) Option {
+	return func(l *Linkage) {
+		l.origins = origins
+	}
+}
+
+// WithDrainTimeouts sets the deadline of each phase of draining when linkage stops
+func WithDrainTimeouts(t DrainTimeouts) Option {
+	return func(l *Linkage) {
+		l.drain = t
+	}
+}
+
+// WithEngineV2 uses e instead of the engine given to InitLinkage, which can be nil
+func WithEngineV2(e EngineV2) Option {
+	return func(l *Linkage) {
+		l.engine = e
+	}
+}
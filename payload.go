@@ -0,0 +1,70 @@
+package linkage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Content types of payload
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Encodings of payload, payload is not encoded if encoding is empty
+const (
+	EncodingIdentity = "identity"
+	EncodingGzip     = "gzip"
+)
+
+// PayloadBytes returns the payload decoded by Encoding,
+// it is Data if it is set, otherwise Payload
+func (j *Job) PayloadBytes() ([]byte, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	b := j.Data
+	if b == nil {
+		b = []byte(j.Payload)
+	}
+
+	switch j.Encoding {
+	case "", EncodingIdentity:
+		return b, nil
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unknown encoding %v", j.Encoding)
+	}
+}
+
+// DecodeJSON decodes the payload as json into v
+func (j *Job) DecodeJSON(v interface{}) error {
+	b, err := j.PayloadBytes()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// DecodeProto decodes the payload as protobuf into msg
+func (j *Job) DecodeProto(msg proto.Message) error {
+	b, err := j.PayloadBytes()
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(b, msg)
+}
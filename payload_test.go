@@ -0,0 +1,61 @@
+package linkage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPayloadBytes(t *testing.T) {
+	cases := []struct {
+		name string
+		job  *Job
+		want string
+		ok   bool
+	}{
+		{"payload", CreateJob(`{"n":1}`, nil), `{"n":1}`, true},
+		{"data", CreateBytesJob([]byte(`{"n":2}`), ContentTypeJSON, nil), `{"n":2}`, true},
+		{"gzip", &Job{Data: gzipped(t, `{"n":3}`), Encoding: EncodingGzip}, `{"n":3}`, true},
+		{"broken gzip", &Job{Data: []byte("plain"), Encoding: EncodingGzip}, "", false},
+		{"unknown encoding", &Job{Payload: "x", Encoding: "br"}, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := tc.job.PayloadBytes()
+			if tc.ok != (err == nil) {
+				t.Fatalf("got error %v, want ok %v", err, tc.ok)
+			}
+			if string(b) != tc.want {
+				t.Fatalf("got %q, want %q", b, tc.want)
+			}
+			if !tc.ok {
+				return
+			}
+
+			var v struct{ N int }
+			if err := tc.job.DecodeJSON(&v); err != nil || v.N == 0 {
+				t.Fatalf("decode json got %v, error %v", v, err)
+			}
+		})
+	}
+}
+
+func TestBytesJobGRPC(t *testing.T) {
+	j := CreateBytesJob([]byte{0, 1, 2, 255}, ContentTypeProtobuf, nil)
+	j.Encoding = EncodingIdentity
+
+	back := toLinkageJob(toGRPCJob(j))
+	if !bytes.Equal(back.Data, j.Data) || back.ContentType != ContentTypeProtobuf || back.Encoding != EncodingIdentity {
+		t.Fatalf("got job %+v, want %+v", back, j)
+	}
+}
@@ -26,6 +26,16 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type Job struct {
 	Payload              string            `protobuf:"bytes,1,opt,name=payload" json:"payload,omitempty"`
 	Metadata             map[string]string `protobuf:"bytes,2,rep,name=metadata" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Id                   string            `protobuf:"bytes,3,opt,name=id" json:"id,omitempty"`
+	RoutingKey           string            `protobuf:"bytes,4,opt,name=routing_key,json=routingKey" json:"routing_key,omitempty"`
+	Priority             int32             `protobuf:"varint,5,opt,name=priority" json:"priority,omitempty"`
+	CreatedAt            int64             `protobuf:"varint,6,opt,name=created_at,json=createdAt" json:"created_at,omitempty"`
+	Deadline             int64             `protobuf:"varint,7,opt,name=deadline" json:"deadline,omitempty"`
+	Data                 []byte            `protobuf:"bytes,8,opt,name=data" json:"data,omitempty"`
+	ContentType          string            `protobuf:"bytes,9,opt,name=content_type,json=contentType" json:"content_type,omitempty"`
+	Encoding             string            `protobuf:"bytes,10,opt,name=encoding" json:"encoding,omitempty"`
+	Compression          string            `protobuf:"bytes,11,opt,name=compression" json:"compression,omitempty"`
+	CompressedPayload    []byte            `protobuf:"bytes,12,opt,name=compressed_payload,json=compressedPayload" json:"compressed_payload,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -35,7 +45,7 @@ func (m *Job) Reset()         { *m = Job{} }
 func (m *Job) String() string { return proto.CompactTextString(m) }
 func (*Job) ProtoMessage()    {}
 func (*Job) Descriptor() ([]byte, []int) {
-	return fileDescriptor_job_2f42ffda08b5bdf2, []int{0}
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{0}
 }
 func (m *Job) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Job.Unmarshal(m, b)
@@ -69,8 +79,84 @@ func (m *Job) GetMetadata() map[string]string {
 	return nil
 }
 
+func (m *Job) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Job) GetRoutingKey() string {
+	if m != nil {
+		return m.RoutingKey
+	}
+	return ""
+}
+
+func (m *Job) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *Job) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Job) GetDeadline() int64 {
+	if m != nil {
+		return m.Deadline
+	}
+	return 0
+}
+
+func (m *Job) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Job) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Job) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+func (m *Job) GetCompression() string {
+	if m != nil {
+		return m.Compression
+	}
+	return ""
+}
+
+func (m *Job) GetCompressedPayload() []byte {
+	if m != nil {
+		return m.CompressedPayload
+	}
+	return nil
+}
+
 type Passphrase struct {
-	Code                 string   `protobuf:"bytes,1,opt,name=code" json:"code,omitempty"`
+	Code string `protobuf:"bytes,1,opt,name=code" json:"code,omitempty"`
+	// topics filter jobs by routing key, "*" matches a word and "#" matches
+	// zero or more words. All jobs are sent if no topic given
+	Topics []string `protobuf:"bytes,2,rep,name=topics" json:"topics,omitempty"`
+	// compressions the downstream accepts in preferred order,
+	// server compresses jobs by the first one it supports
+	Compressions         []string `protobuf:"bytes,3,rep,name=compressions" json:"compressions,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -80,7 +166,7 @@ func (m *Passphrase) Reset()         { *m = Passphrase{} }
 func (m *Passphrase) String() string { return proto.CompactTextString(m) }
 func (*Passphrase) ProtoMessage()    {}
 func (*Passphrase) Descriptor() ([]byte, []int) {
-	return fileDescriptor_job_2f42ffda08b5bdf2, []int{1}
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{1}
 }
 func (m *Passphrase) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Passphrase.Unmarshal(m, b)
@@ -107,10 +193,279 @@ func (m *Passphrase) GetCode() string {
 	return ""
 }
 
+func (m *Passphrase) GetTopics() []string {
+	if m != nil {
+		return m.Topics
+	}
+	return nil
+}
+
+func (m *Passphrase) GetCompressions() []string {
+	if m != nil {
+		return m.Compressions
+	}
+	return nil
+}
+
+type Feedback struct {
+	Passphrase *Passphrase `protobuf:"bytes,1,opt,name=passphrase" json:"passphrase,omitempty"`
+	Ack        *Ack        `protobuf:"bytes,2,opt,name=ack" json:"ack,omitempty"`
+	// credit grants the server to send more jobs. Once granted, the server
+	// pauses when credit runs out. No flow control if never granted
+	Credit               uint32   `protobuf:"varint,3,opt,name=credit" json:"credit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Feedback) Reset()         { *m = Feedback{} }
+func (m *Feedback) String() string { return proto.CompactTextString(m) }
+func (*Feedback) ProtoMessage()    {}
+func (*Feedback) Descriptor() ([]byte, []int) {
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{2}
+}
+func (m *Feedback) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Feedback.Unmarshal(m, b)
+}
+func (m *Feedback) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Feedback.Marshal(b, m, deterministic)
+}
+func (dst *Feedback) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Feedback.Merge(dst, src)
+}
+func (m *Feedback) XXX_Size() int {
+	return xxx_messageInfo_Feedback.Size(m)
+}
+func (m *Feedback) XXX_DiscardUnknown() {
+	xxx_messageInfo_Feedback.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Feedback proto.InternalMessageInfo
+
+func (m *Feedback) GetPassphrase() *Passphrase {
+	if m != nil {
+		return m.Passphrase
+	}
+	return nil
+}
+
+func (m *Feedback) GetAck() *Ack {
+	if m != nil {
+		return m.Ack
+	}
+	return nil
+}
+
+func (m *Feedback) GetCredit() uint32 {
+	if m != nil {
+		return m.Credit
+	}
+	return 0
+}
+
+// Ack confirms a job is received, set nack to ask for redelivery
+type Ack struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Nack                 bool     `protobuf:"varint,2,opt,name=nack" json:"nack,omitempty"`
+	Reason               string   `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Ack) Reset()         { *m = Ack{} }
+func (m *Ack) String() string { return proto.CompactTextString(m) }
+func (*Ack) ProtoMessage()    {}
+func (*Ack) Descriptor() ([]byte, []int) {
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{3}
+}
+func (m *Ack) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ack.Unmarshal(m, b)
+}
+func (m *Ack) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ack.Marshal(b, m, deterministic)
+}
+func (dst *Ack) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ack.Merge(dst, src)
+}
+func (m *Ack) XXX_Size() int {
+	return xxx_messageInfo_Ack.Size(m)
+}
+func (m *Ack) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ack.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ack proto.InternalMessageInfo
+
+func (m *Ack) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Ack) GetNack() bool {
+	if m != nil {
+		return m.Nack
+	}
+	return false
+}
+
+func (m *Ack) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type SubmitRequest struct {
+	Passphrase           *Passphrase `protobuf:"bytes,1,opt,name=passphrase" json:"passphrase,omitempty"`
+	Job                  *Job        `protobuf:"bytes,2,opt,name=job" json:"job,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *SubmitRequest) Reset()         { *m = SubmitRequest{} }
+func (m *SubmitRequest) String() string { return proto.CompactTextString(m) }
+func (*SubmitRequest) ProtoMessage()    {}
+func (*SubmitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{4}
+}
+func (m *SubmitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubmitRequest.Unmarshal(m, b)
+}
+func (m *SubmitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubmitRequest.Marshal(b, m, deterministic)
+}
+func (dst *SubmitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubmitRequest.Merge(dst, src)
+}
+func (m *SubmitRequest) XXX_Size() int {
+	return xxx_messageInfo_SubmitRequest.Size(m)
+}
+func (m *SubmitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubmitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubmitRequest proto.InternalMessageInfo
+
+func (m *SubmitRequest) GetPassphrase() *Passphrase {
+	if m != nil {
+		return m.Passphrase
+	}
+	return nil
+}
+
+func (m *SubmitRequest) GetJob() *Job {
+	if m != nil {
+		return m.Job
+	}
+	return nil
+}
+
+// Receipt tells if a submitted job is accepted, reason is set if rejected
+type Receipt struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Accepted             bool     `protobuf:"varint,2,opt,name=accepted" json:"accepted,omitempty"`
+	Reason               string   `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Receipt) Reset()         { *m = Receipt{} }
+func (m *Receipt) String() string { return proto.CompactTextString(m) }
+func (*Receipt) ProtoMessage()    {}
+func (*Receipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{5}
+}
+func (m *Receipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Receipt.Unmarshal(m, b)
+}
+func (m *Receipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Receipt.Marshal(b, m, deterministic)
+}
+func (dst *Receipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Receipt.Merge(dst, src)
+}
+func (m *Receipt) XXX_Size() int {
+	return xxx_messageInfo_Receipt.Size(m)
+}
+func (m *Receipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_Receipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Receipt proto.InternalMessageInfo
+
+func (m *Receipt) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Receipt) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+func (m *Receipt) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type SubmitResponse struct {
+	Receipts             []*Receipt `protobuf:"bytes,1,rep,name=receipts" json:"receipts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *SubmitResponse) Reset()         { *m = SubmitResponse{} }
+func (m *SubmitResponse) String() string { return proto.CompactTextString(m) }
+func (*SubmitResponse) ProtoMessage()    {}
+func (*SubmitResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_job_dbfb35b1a15276b3, []int{6}
+}
+func (m *SubmitResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubmitResponse.Unmarshal(m, b)
+}
+func (m *SubmitResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubmitResponse.Marshal(b, m, deterministic)
+}
+func (dst *SubmitResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubmitResponse.Merge(dst, src)
+}
+func (m *SubmitResponse) XXX_Size() int {
+	return xxx_messageInfo_SubmitResponse.Size(m)
+}
+func (m *SubmitResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubmitResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubmitResponse proto.InternalMessageInfo
+
+func (m *SubmitResponse) GetReceipts() []*Receipt {
+	if m != nil {
+		return m.Receipts
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Job)(nil), "job.Job")
 	proto.RegisterMapType((map[string]string)(nil), "job.Job.MetadataEntry")
 	proto.RegisterType((*Passphrase)(nil), "job.Passphrase")
+	proto.RegisterType((*Feedback)(nil), "job.Feedback")
+	proto.RegisterType((*Ack)(nil), "job.Ack")
+	proto.RegisterType((*SubmitRequest)(nil), "job.SubmitRequest")
+	proto.RegisterType((*Receipt)(nil), "job.Receipt")
+	proto.RegisterType((*SubmitResponse)(nil), "job.SubmitResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -125,7 +480,13 @@ const _ = grpc.SupportPackageIsVersion4
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ServiceClient interface {
-	Ask(ctx context.Context, in *Passphrase, opts ...grpc.CallOption) (Service_AskClient, error)
+	// Ask opens a job stream. The first Feedback must carry the passphrase,
+	// the following ones acknowledge the received jobs.
+	Ask(ctx context.Context, opts ...grpc.CallOption) (Service_AskClient, error)
+	// Submit pushes jobs into the server. The first SubmitRequest must carry
+	// the passphrase, each request may carry a job. The response tells which
+	// jobs are accepted in the order they were sent.
+	Submit(ctx context.Context, opts ...grpc.CallOption) (Service_SubmitClient, error)
 }
 
 type serviceClient struct {
@@ -136,22 +497,17 @@ func NewServiceClient(cc *grpc.ClientConn) ServiceClient {
 	return &serviceClient{cc}
 }
 
-func (c *serviceClient) Ask(ctx context.Context, in *Passphrase, opts ...grpc.CallOption) (Service_AskClient, error) {
+func (c *serviceClient) Ask(ctx context.Context, opts ...grpc.CallOption) (Service_AskClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_Service_serviceDesc.Streams[0], "/job.Service/Ask", opts...)
 	if err != nil {
 		return nil, err
 	}
 	x := &serviceAskClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
 	return x, nil
 }
 
 type Service_AskClient interface {
+	Send(*Feedback) error
 	Recv() (*Job, error)
 	grpc.ClientStream
 }
@@ -160,6 +516,10 @@ type serviceAskClient struct {
 	grpc.ClientStream
 }
 
+func (x *serviceAskClient) Send(m *Feedback) error {
+	return x.ClientStream.SendMsg(m)
+}
+
 func (x *serviceAskClient) Recv() (*Job, error) {
 	m := new(Job)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
@@ -168,9 +528,49 @@ func (x *serviceAskClient) Recv() (*Job, error) {
 	return m, nil
 }
 
+func (c *serviceClient) Submit(ctx context.Context, opts ...grpc.CallOption) (Service_SubmitClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Service_serviceDesc.Streams[1], "/job.Service/Submit", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceSubmitClient{stream}
+	return x, nil
+}
+
+type Service_SubmitClient interface {
+	Send(*SubmitRequest) error
+	CloseAndRecv() (*SubmitResponse, error)
+	grpc.ClientStream
+}
+
+type serviceSubmitClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceSubmitClient) Send(m *SubmitRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *serviceSubmitClient) CloseAndRecv() (*SubmitResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SubmitResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ServiceServer is the server API for Service service.
 type ServiceServer interface {
-	Ask(*Passphrase, Service_AskServer) error
+	// Ask opens a job stream. The first Feedback must carry the passphrase,
+	// the following ones acknowledge the received jobs.
+	Ask(Service_AskServer) error
+	// Submit pushes jobs into the server. The first SubmitRequest must carry
+	// the passphrase, each request may carry a job. The response tells which
+	// jobs are accepted in the order they were sent.
+	Submit(Service_SubmitServer) error
 }
 
 func RegisterServiceServer(s *grpc.Server, srv ServiceServer) {
@@ -178,15 +578,12 @@ func RegisterServiceServer(s *grpc.Server, srv ServiceServer) {
 }
 
 func _Service_Ask_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(Passphrase)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
-	}
-	return srv.(ServiceServer).Ask(m, &serviceAskServer{stream})
+	return srv.(ServiceServer).Ask(&serviceAskServer{stream})
 }
 
 type Service_AskServer interface {
 	Send(*Job) error
+	Recv() (*Feedback, error)
 	grpc.ServerStream
 }
 
@@ -198,6 +595,40 @@ func (x *serviceAskServer) Send(m *Job) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func (x *serviceAskServer) Recv() (*Feedback, error) {
+	m := new(Feedback)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Service_Submit_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ServiceServer).Submit(&serviceSubmitServer{stream})
+}
+
+type Service_SubmitServer interface {
+	SendAndClose(*SubmitResponse) error
+	Recv() (*SubmitRequest, error)
+	grpc.ServerStream
+}
+
+type serviceSubmitServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceSubmitServer) SendAndClose(m *SubmitResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *serviceSubmitServer) Recv() (*SubmitRequest, error) {
+	m := new(SubmitRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Service_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "job.Service",
 	HandlerType: (*ServiceServer)(nil),
@@ -207,26 +638,55 @@ var _Service_serviceDesc = grpc.ServiceDesc{
 			StreamName:    "Ask",
 			Handler:       _Service_Ask_Handler,
 			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Submit",
+			Handler:       _Service_Submit_Handler,
+			ClientStreams: true,
 		},
 	},
 	Metadata: "job.proto",
 }
 
-func init() { proto.RegisterFile("job.proto", fileDescriptor_job_2f42ffda08b5bdf2) }
-
-var fileDescriptor_job_2f42ffda08b5bdf2 = []byte{
-	// 205 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xcc, 0xca, 0x4f, 0xd2,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0xce, 0xca, 0x4f, 0x52, 0x9a, 0xc0, 0xc8, 0xc5, 0xec,
-	0x95, 0x9f, 0x24, 0x24, 0xc1, 0xc5, 0x5e, 0x90, 0x58, 0x99, 0x93, 0x9f, 0x98, 0x22, 0xc1, 0xa8,
-	0xc0, 0xa8, 0xc1, 0x19, 0x04, 0xe3, 0x0a, 0x19, 0x71, 0x71, 0xe4, 0xa6, 0x96, 0x24, 0xa6, 0x24,
-	0x96, 0x24, 0x4a, 0x30, 0x29, 0x30, 0x6b, 0x70, 0x1b, 0x89, 0xe9, 0x81, 0x0c, 0xf1, 0xca, 0x4f,
-	0xd2, 0xf3, 0x85, 0x4a, 0xb8, 0xe6, 0x95, 0x14, 0x55, 0x06, 0xc1, 0xd5, 0x49, 0x59, 0x73, 0xf1,
-	0xa2, 0x48, 0x09, 0x09, 0x70, 0x31, 0x67, 0xa7, 0x56, 0x42, 0x8d, 0x06, 0x31, 0x85, 0x44, 0xb8,
-	0x58, 0xcb, 0x12, 0x73, 0x4a, 0x53, 0x25, 0x98, 0xc0, 0x62, 0x10, 0x8e, 0x15, 0x93, 0x05, 0xa3,
-	0x92, 0x02, 0x17, 0x57, 0x40, 0x62, 0x71, 0x71, 0x41, 0x46, 0x51, 0x62, 0x71, 0xaa, 0x90, 0x10,
-	0x17, 0x4b, 0x72, 0x7e, 0x4a, 0x2a, 0x54, 0x2b, 0x98, 0x6d, 0xa4, 0xcf, 0xc5, 0x1e, 0x9c, 0x5a,
-	0x54, 0x96, 0x99, 0x9c, 0x2a, 0xa4, 0xc2, 0xc5, 0xec, 0x58, 0x9c, 0x2d, 0xc4, 0x0f, 0x76, 0x12,
-	0x42, 0x9b, 0x14, 0x07, 0xcc, 0x8d, 0x4a, 0x0c, 0x06, 0x8c, 0x49, 0x6c, 0x60, 0x1f, 0x1b, 0x03,
-	0x02, 0x00, 0x00, 0xff, 0xff, 0x5f, 0xac, 0x61, 0xdf, 0xfe, 0x00, 0x00, 0x00,
+func init() { proto.RegisterFile("job.proto", fileDescriptor_job_dbfb35b1a15276b3) }
+
+var fileDescriptor_job_dbfb35b1a15276b3 = []byte{
+	// 561 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x94, 0xc1, 0x6f, 0xd3, 0x30,
+	0x14, 0xc6, 0x49, 0xd3, 0xb5, 0xe9, 0x4b, 0x3b, 0xe0, 0x81, 0x26, 0xab, 0x12, 0xa2, 0x44, 0x1c,
+	0x72, 0x61, 0xa0, 0x22, 0x24, 0x34, 0x4e, 0x3d, 0xc0, 0x61, 0x68, 0xd2, 0xe4, 0x71, 0x9c, 0x54,
+	0x39, 0xce, 0x63, 0x78, 0x5d, 0xed, 0x10, 0xbb, 0x93, 0xf2, 0xbf, 0xf1, 0xc7, 0xa1, 0xb8, 0x4e,
+	0x46, 0x85, 0xb8, 0x70, 0xf3, 0xf7, 0x7d, 0xf6, 0x7b, 0xbf, 0xda, 0x2f, 0x85, 0xc9, 0xad, 0x29,
+	0x4e, 0xab, 0xda, 0x38, 0x83, 0xf1, 0xad, 0x29, 0xb2, 0x5f, 0x31, 0xc4, 0xe7, 0xa6, 0x40, 0x06,
+	0xe3, 0x4a, 0x34, 0x77, 0x46, 0x94, 0x2c, 0x5a, 0x44, 0xf9, 0x84, 0x77, 0x12, 0x97, 0x90, 0x6c,
+	0xc9, 0x89, 0x52, 0x38, 0xc1, 0x06, 0x8b, 0x38, 0x4f, 0x97, 0x27, 0xa7, 0x6d, 0x91, 0x73, 0x53,
+	0x9c, 0x5e, 0x84, 0xe0, 0xb3, 0x76, 0x75, 0xc3, 0xfb, 0x7d, 0x78, 0x0c, 0x03, 0x55, 0xb2, 0xd8,
+	0x17, 0x1a, 0xa8, 0x12, 0x5f, 0x42, 0x5a, 0x9b, 0x9d, 0x53, 0xfa, 0x66, 0xbd, 0xa1, 0x86, 0x0d,
+	0x7d, 0x00, 0xc1, 0xfa, 0x4a, 0x0d, 0xce, 0x21, 0xa9, 0x6a, 0x65, 0x6a, 0xe5, 0x1a, 0x76, 0xb4,
+	0x88, 0xf2, 0x23, 0xde, 0x6b, 0x7c, 0x01, 0x20, 0x6b, 0x12, 0x8e, 0xca, 0xb5, 0x70, 0x6c, 0xb4,
+	0x88, 0xf2, 0x98, 0x4f, 0x82, 0xb3, 0x72, 0xed, 0xd1, 0x92, 0x44, 0x79, 0xa7, 0x34, 0xb1, 0xb1,
+	0x0f, 0x7b, 0x8d, 0x08, 0x43, 0xcf, 0x9d, 0x2c, 0xa2, 0x7c, 0xca, 0xfd, 0x1a, 0x5f, 0xc1, 0x54,
+	0x1a, 0xed, 0x48, 0xbb, 0xb5, 0x6b, 0x2a, 0x62, 0x13, 0x0f, 0x93, 0x06, 0xef, 0x5b, 0x53, 0x51,
+	0x5b, 0x92, 0xb4, 0x34, 0xa5, 0xd2, 0x37, 0x0c, 0x7c, 0xdc, 0x6b, 0x5c, 0x40, 0x2a, 0xcd, 0xb6,
+	0xaa, 0xc9, 0x5a, 0x65, 0x34, 0x4b, 0xbb, 0xd3, 0xbd, 0x85, 0x6f, 0x00, 0x3b, 0x49, 0xe5, 0xba,
+	0xbb, 0xd5, 0xa9, 0x47, 0x78, 0xfa, 0x90, 0x5c, 0xee, 0x83, 0xf9, 0x27, 0x98, 0x1d, 0x5c, 0x23,
+	0x3e, 0x81, 0xb8, 0xbd, 0xa4, 0xfd, 0x33, 0xb4, 0x4b, 0x7c, 0x0e, 0x47, 0xf7, 0xe2, 0x6e, 0x47,
+	0x6c, 0xe0, 0xbd, 0xbd, 0x38, 0x1b, 0x7c, 0x8c, 0xb2, 0x6b, 0x80, 0x4b, 0x61, 0x6d, 0xf5, 0xa3,
+	0x16, 0xd6, 0xff, 0x5c, 0x69, 0x4a, 0x0a, 0x47, 0xfd, 0x1a, 0x4f, 0x60, 0xe4, 0x4c, 0xa5, 0xa4,
+	0xf5, 0x8f, 0x37, 0xe1, 0x41, 0x61, 0x06, 0xd3, 0x8e, 0x45, 0x19, 0x6d, 0x59, 0xec, 0xd3, 0x03,
+	0x2f, 0x33, 0x90, 0x7c, 0x21, 0x2a, 0x0b, 0x21, 0x37, 0xf8, 0x16, 0xa0, 0xea, 0x3b, 0xf9, 0x0e,
+	0xe9, 0xf2, 0xb1, 0x1f, 0x84, 0x07, 0x00, 0xfe, 0xc7, 0x16, 0x9c, 0x43, 0x2c, 0xe4, 0xc6, 0x23,
+	0xa7, 0xcb, 0xc4, 0xef, 0x5c, 0xc9, 0x0d, 0x6f, 0xcd, 0x16, 0x4a, 0xd6, 0x54, 0x2a, 0xe7, 0x67,
+	0x64, 0xc6, 0x83, 0xca, 0x56, 0x10, 0xaf, 0xe4, 0x26, 0x8c, 0x4f, 0xd4, 0x8f, 0x0f, 0xc2, 0x50,
+	0x77, 0xb5, 0x12, 0x3e, 0xd4, 0xa1, 0x44, 0x4d, 0xc2, 0x1a, 0x1d, 0xc6, 0x2c, 0xa8, 0xec, 0x1a,
+	0x66, 0x57, 0xbb, 0x62, 0xab, 0x1c, 0xa7, 0x9f, 0x3b, 0xb2, 0xee, 0xbf, 0xc0, 0x6f, 0x4d, 0x71,
+	0x00, 0x7e, 0x6e, 0x0a, 0xee, 0x3f, 0x97, 0x0b, 0x18, 0x73, 0x92, 0xa4, 0x2a, 0xf7, 0x17, 0xe4,
+	0x1c, 0x12, 0x21, 0x25, 0x55, 0x8e, 0xca, 0x00, 0xda, 0xeb, 0x7f, 0xc2, 0x9e, 0xc1, 0x71, 0x07,
+	0x6b, 0x2b, 0xa3, 0x2d, 0x61, 0x0e, 0x49, 0xbd, 0x6f, 0x60, 0x59, 0xe4, 0xbf, 0xb6, 0xa9, 0x27,
+	0x08, 0x5d, 0x79, 0x9f, 0x2e, 0xbf, 0xc3, 0xf8, 0x8a, 0xea, 0x7b, 0x25, 0x09, 0x5f, 0x43, 0xbc,
+	0xb2, 0x1b, 0x9c, 0xf9, 0x9d, 0xdd, 0x8b, 0xcd, 0x7b, 0xf4, 0xec, 0x51, 0x1e, 0xbd, 0x8b, 0xf0,
+	0x03, 0x8c, 0xf6, 0xcd, 0x10, 0x7d, 0x72, 0x70, 0x4d, 0xf3, 0x67, 0x07, 0xde, 0x9e, 0xa6, 0x3d,
+	0x58, 0x8c, 0xfc, 0xbf, 0xc5, 0xfb, 0xdf, 0x03, 0x00, 0x95, 0xb7, 0x0e, 0x18, 0x3a, 0x04, 0x00,
+	0x00,
 }
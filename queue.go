@@ -0,0 +1,115 @@
+package linkage
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// jobQueue is a goroutine-safe priority queue of jobs,
+// jobs with the same priority are popped in FIFO order.
+// ready receives a value whenever the queue may have jobs to pop
+type jobQueue struct {
+	mu    sync.Mutex
+	jobs  jobHeap
+	seq   uint64
+	ready chan struct{}
+}
+
+func newJobQueue() *jobQueue {
+	return &jobQueue{
+		ready: make(chan struct{}, 1),
+	}
+}
+
+func (q *jobQueue) push(jobs ...*Job) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	for _, j := range jobs {
+		q.seq++
+		heap.Push(&q.jobs, queued{
+			job: j,
+			seq: q.seq,
+		})
+	}
+	q.mu.Unlock()
+	q.notify()
+}
+
+// pop returns nil if queue is empty
+func (q *jobQueue) pop() *Job {
+	q.mu.Lock()
+	if len(q.jobs) == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	j := heap.Pop(&q.jobs).(queued).job
+	left := len(q.jobs)
+	q.mu.Unlock()
+
+	// wake up the next waiting consumer
+	if left > 0 {
+		q.notify()
+	}
+	return j
+}
+
+// drain pops all jobs in queue in order
+func (q *jobQueue) drain() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(q.jobs))
+	for len(q.jobs) > 0 {
+		jobs = append(jobs, heap.Pop(&q.jobs).(queued).job)
+	}
+	return jobs
+}
+
+func (q *jobQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+func (q *jobQueue) notify() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// queued is a job and the order it is pushed
+type queued struct {
+	job *Job
+	seq uint64
+}
+
+// jobHeap implements heap.Interface
+type jobHeap []queued
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, k int) bool {
+	if h[i].job.Priority != h[k].job.Priority {
+		return h[i].job.Priority > h[k].job.Priority
+	}
+	return h[i].seq < h[k].seq
+}
+
+func (h jobHeap) Swap(i, k int) { h[i], h[k] = h[k], h[i] }
+
+func (h *jobHeap) Push(x interface{}) {
+	*h = append(*h, x.(queued))
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = queued{}
+	*h = old[:n-1]
+	return x
+}
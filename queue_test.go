@@ -0,0 +1,62 @@
+package linkage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJobQueueOrder(t *testing.T) {
+	type pushed struct {
+		id       string
+		priority int32
+	}
+	cases := []struct {
+		name string
+		push []pushed
+		want []string
+	}{
+		{
+			name: "fifo",
+			push: []pushed{{"a", 0}, {"b", 0}, {"c", 0}},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "priority",
+			push: []pushed{{"low", -1}, {"mid", 0}, {"high", 5}},
+			want: []string{"high", "mid", "low"},
+		},
+		{
+			name: "fifo in priority",
+			push: []pushed{{"a1", 1}, {"b0", 0}, {"a2", 1}, {"c2", 2}, {"b1", 0}, {"a3", 1}},
+			want: []string{"c2", "a1", "a2", "a3", "b0", "b1"},
+		},
+	}
+
+	for _, c := range cases {
+		q := newJobQueue()
+		for _, p := range c.push {
+			q.push(&Job{ID: p.id, Priority: p.priority})
+		}
+
+		var got []string
+		for j := q.pop(); j != nil; j = q.pop() {
+			got = append(got, j.ID)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: popped %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestJobQueueDrain(t *testing.T) {
+	q := newJobQueue()
+	q.push(&Job{ID: "a"}, &Job{ID: "b", Priority: 1})
+
+	jobs := q.drain()
+	if len(jobs) != 2 || jobs[0].ID != "b" || jobs[1].ID != "a" {
+		t.Fatalf("drained %v", jobs)
+	}
+	if q.len() != 0 || q.pop() != nil {
+		t.Fatal("queue is not empty after drain")
+	}
+}
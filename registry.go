@@ -0,0 +1,38 @@
+package linkage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// EngineFactory creates an engine by the config
+type EngineFactory func(cfg map[string]interface{}) (Engine, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]EngineFactory)
+)
+
+// RegisterEngine makes the engine factory available by name,
+// it panics if the name is registered twice
+func RegisterEngine(name string, factory EngineFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("linkage: engine %v registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// NewEngine creates the engine registered by name
+func NewEngine(name string, cfg map[string]interface{}) (Engine, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %v", name)
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,31 @@
+package linkage
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	var got map[string]interface{}
+	RegisterEngine("registry-test", func(cfg map[string]interface{}) (Engine, error) {
+		got = cfg
+		return &testEngine{}, nil
+	})
+
+	e, err := NewEngine("registry-test", map[string]interface{}{"buffer": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(*testEngine); !ok || got["buffer"] != 2 {
+		t.Fatalf("got engine %T created with %v", e, got)
+	}
+
+	_, err = NewEngine("no-such-engine", nil)
+	if err == nil {
+		t.Fatal("unknown engine created")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("engine registered twice")
+		}
+	}()
+	RegisterEngine("registry-test", nil)
+}
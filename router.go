@@ -0,0 +1,149 @@
+package linkage
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// subscriber is a downstream stream and the topics it subscribes,
+// it subscribes all jobs if no topic given
+type subscriber struct {
+	topics []string
+	queue  *jobQueue
+}
+
+func (sub *subscriber) match(j *Job) bool {
+	if len(sub.topics) == 0 {
+		return true
+	}
+
+	for _, t := range sub.topics {
+		if MatchTopic(t, j.RoutingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxUnrouted is the max number of jobs kept for no subscriber
+const maxUnrouted = 1024
+
+// router routes jobs to the subscriber with least queued jobs in matched ones,
+// jobs no one subscribes are kept until a matched subscriber comes,
+// at most maxUnrouted of them, others are given to overflow
+type router struct {
+	mu       sync.Mutex
+	subs     []*subscriber
+	unrouted []*Job
+	overflow func(j *Job)
+}
+
+func newRouter(overflow func(j *Job)) *router {
+	return &router{
+		overflow: overflow,
+	}
+}
+
+func (r *router) subscribe(topics []string) *subscriber {
+	sub := &subscriber{
+		topics: topics,
+		queue:  newJobQueue(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subs = append(r.subs, sub)
+
+	// take the jobs waiting for this subscriber
+	left := r.unrouted[:0]
+	for _, j := range r.unrouted {
+		if sub.match(j) {
+			sub.queue.push(j)
+		} else {
+			left = append(left, j)
+		}
+	}
+	for i := len(left); i < len(r.unrouted); i++ {
+		r.unrouted[i] = nil
+	}
+	r.unrouted = left
+	return sub
+}
+
+// unsubscribe removes the subscriber and routes jobs left in its queue
+func (r *router) unsubscribe(sub *subscriber) {
+	r.mu.Lock()
+	for i, s := range r.subs {
+		if s == sub {
+			r.subs = append(r.subs[:i], r.subs[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	r.route(sub.queue.drain()...)
+}
+
+func (r *router) route(jobs ...*Job) {
+	var dropped []*Job
+	r.mu.Lock()
+	for _, j := range jobs {
+		target := r.target(j)
+		if target == nil {
+			if len(r.unrouted) >= maxUnrouted {
+				dropped = append(dropped, j)
+				continue
+			}
+			log.WithFields(log.Fields{
+				"id":          j.ID,
+				"routing_key": j.RoutingKey,
+			}).Warn("no subscriber for job, keep it until one comes")
+			r.unrouted = append(r.unrouted, j)
+			continue
+		}
+		target.queue.push(j)
+	}
+	r.mu.Unlock()
+
+	// overflow may put dead letters over network, not hold the lock for it
+	for _, j := range dropped {
+		r.overflow(j)
+	}
+}
+
+// forward queues the job to a matched subscriber,
+// it returns false if no subscriber matches the job
+func (r *router) forward(j *Job) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	target := r.target(j)
+	if target == nil {
+		return false
+	}
+	target.queue.push(j)
+	return true
+}
+
+// target returns the matched subscriber with least queued jobs, r.mu is held
+func (r *router) target(j *Job) *subscriber {
+	var target *subscriber
+	for _, sub := range r.subs {
+		if !sub.match(j) {
+			continue
+		}
+		if target == nil || sub.queue.len() < target.queue.len() {
+			target = sub
+		}
+	}
+	return target
+}
+
+// unroutedLen returns the number of jobs no subscriber matches
+func (r *router) unroutedLen() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.unrouted)
+}
@@ -0,0 +1,191 @@
+package linkage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestRouterRoute(t *testing.T) {
+	r := newRouter(nil)
+	orders := r.subscribe([]string{"orders.*"})
+	logs := r.subscribe([]string{"logs.#"})
+	all := r.subscribe(nil)
+
+	cases := []struct {
+		key  string
+		want []*subscriber
+	}{
+		{"orders.new", []*subscriber{orders, all}},
+		{"logs.app.error", []*subscriber{logs, all}},
+		{"metrics", []*subscriber{all}},
+	}
+
+	for _, c := range cases {
+		before := make([]int, len(c.want))
+		for i, sub := range c.want {
+			before[i] = sub.queue.len()
+		}
+
+		r.route(&Job{ID: c.key, RoutingKey: c.key})
+
+		// the job goes to one of the matched subscribers
+		taken := 0
+		for i, sub := range c.want {
+			taken += sub.queue.len() - before[i]
+		}
+		if taken != 1 {
+			t.Errorf("%v: taken by %v matched subscribers, want 1", c.key, taken)
+		}
+	}
+	if n := orders.queue.len() + logs.queue.len() + all.queue.len(); n != len(cases) {
+		t.Errorf("%v jobs queued, want %v", n, len(cases))
+	}
+}
+
+func TestRouterLeastQueued(t *testing.T) {
+	r := newRouter(nil)
+	a := r.subscribe([]string{"k"})
+	b := r.subscribe([]string{"k"})
+
+	for i := 0; i < 4; i++ {
+		r.route(&Job{RoutingKey: "k"})
+	}
+	if a.queue.len() != 2 || b.queue.len() != 2 {
+		t.Fatalf("queued %v and %v, want 2 and 2", a.queue.len(), b.queue.len())
+	}
+}
+
+func TestRouterUnrouted(t *testing.T) {
+	r := newRouter(nil)
+	r.route(&Job{ID: "a", RoutingKey: "orders.new"}, &Job{ID: "b", RoutingKey: "logs.app"})
+	if n := len(r.unrouted); n != 2 {
+		t.Fatalf("%v jobs unrouted, want 2", n)
+	}
+
+	// the job waits for the subscriber matches it
+	sub := r.subscribe([]string{"orders.*"})
+	if j := sub.queue.pop(); j == nil || j.ID != "a" {
+		t.Fatalf("subscriber got %v, want a", j.GetID())
+	}
+	if n := len(r.unrouted); n != 1 {
+		t.Fatalf("%v jobs unrouted, want 1", n)
+	}
+
+	// jobs left in the queue of the subscriber gone are routed again
+	sub.queue.push(&Job{ID: "c", RoutingKey: "orders.old"})
+	r.unsubscribe(sub)
+	if n := len(r.unrouted); n != 2 {
+		t.Fatalf("%v jobs unrouted after unsubscribe, want 2", n)
+	}
+}
+
+func TestRouterOverflow(t *testing.T) {
+	var dropped []*Job
+	r := newRouter(func(j *Job) {
+		dropped = append(dropped, j)
+	})
+	for i := 0; i < maxUnrouted+2; i++ {
+		r.route(&Job{RoutingKey: "nobody"})
+	}
+
+	if n := r.unroutedLen(); n != maxUnrouted {
+		t.Errorf("%v jobs unrouted, want %v", n, maxUnrouted)
+	}
+	if len(dropped) != 2 {
+		t.Errorf("%v jobs overflowed, want 2", len(dropped))
+	}
+}
+
+func TestRouterForward(t *testing.T) {
+	r := newRouter(nil)
+	orders := r.subscribe([]string{"orders.*"})
+
+	if !r.forward(&Job{ID: "a", RoutingKey: "orders.new"}) {
+		t.Fatal("job not forwarded to matched subscriber")
+	}
+	if j := orders.queue.pop(); j == nil || j.ID != "a" {
+		t.Fatalf("subscriber got %v, want a", j.GetID())
+	}
+
+	// the job no one matches is not kept
+	if r.forward(&Job{ID: "b", RoutingKey: "logs.app"}) {
+		t.Fatal("job forwarded without matched subscriber")
+	}
+	if n := r.unroutedLen(); n != 0 {
+		t.Fatalf("%v jobs unrouted, want 0", n)
+	}
+}
+
+// topicEngine sends the jobs of its channel to the downstream subscribes topic
+type topicEngine struct {
+	topic string
+	jobs  chan *Job
+}
+
+func (e *topicEngine) Start(context.Context, <-chan *Job) error { return nil }
+
+func (e *topicEngine) Register(ctx context.Context, info RegisterInfo) (<-chan *Job, error) {
+	go func() {
+		for range info.Signal {
+		}
+	}()
+	if len(info.Topics) == 1 && info.Topics[0] == e.topic {
+		return e.jobs, nil
+	}
+	return make(chan *Job), nil
+}
+
+func TestServerUnmatched(t *testing.T) {
+	e := &topicEngine{
+		topic: "a",
+		jobs:  make(chan *Job, 2),
+	}
+	sink := InitMemoryDeadLetters(0)
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:       addr,
+		EngineV2:   e,
+		CodeAssert: func(Code) bool { return true },
+		DeadLetter: sink,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	var cs []*Client
+	for _, topic := range []string{"a", "b"} {
+		c := dialTest(t, &DialInfo{
+			Addr:   addr,
+			Opts:   []grpc.DialOption{grpc.WithInsecure()},
+			Topics: []string{topic},
+		})
+		defer c.Close()
+		cs = append(cs, c)
+	}
+	for i := 0; len(srv.downstreams()) < 2; i++ {
+		if i == 50 {
+			t.Fatal("streams not subscribed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// the engine sends jobs of b and c to the stream of a
+	e.jobs <- &Job{ID: "b", RoutingKey: "b"}
+	e.jobs <- &Job{ID: "c", RoutingKey: "c"}
+
+	if j := askTimeout(t, cs[1], time.Second); j == nil || j.ID != "b" {
+		t.Fatalf("stream of b got %v, want b", j.GetID())
+	}
+	if j := askTimeout(t, cs[0], 300*time.Millisecond); j != nil {
+		t.Fatalf("stream of a got %v", j.ID)
+	}
+	letters := sink.Letters()
+	if len(letters) != 1 || letters[0].Job.ID != "c" || letters[0].Reason != "unmatched" {
+		t.Fatalf("got letters %+v, want c unmatched", letters)
+	}
+}
@@ -1,23 +1,40 @@
 package linkage
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"linkage/proto/admin"
 	"linkage/proto/job"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
 // Server implement JobServiceServer and use JobServiceClient
 // to recieve job and accept stream request
 type Server struct {
-	cfg   *ServerConfig
-	close Done
-	wg    sync.WaitGroup
+	cfg       *ServerConfig
+	engine    EngineV2
+	close     Done
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+	router    *router
+	metrics   Metrics
+	admin     admin.AdminServer
+	submit    func(j *Job, source string) error
+	streamsMu sync.Mutex
+	streams   map[string]*downstream
 }
 
 // Result struct
@@ -27,26 +44,84 @@ type Result struct {
 }
 
 // ServerConfig struct
+// Auth authenticates downstreams instead of CodeAssert if it is set.
+// Metrics is optional, nothing is recorded if it is nil.
+// Buffer is the max number of jobs taken from engine waiting for each stream,
+// jobs with higher priority in them are sent first.
+// Expired takes jobs past the deadline, they are dropped if it is nil.
+// Jobs sent MaxAttempts times but not acked are put to DeadLetter,
+// they are redelivered until acked if MaxAttempts is less than 1.
+// Compressions are allowed for jobs if downstream accepts,
+// jobs smaller than MinCompressSize bytes are not compressed.
+// Jobs submitted compressed over MaxJobSize bytes after decompressed are rejected, default is 16MB.
+// A Submit stream ends once MaxSubmitJobs jobs are received, default is 1000.
+// Exporter exports spans of sending jobs, nothing is exported if it is nil.
+// DrainTimeout is how long each stream flushes left jobs and waits for acks
+// when the server is closing, default is 2 seconds.
+// EngineV2 is used instead of Engine if it is set
 type ServerConfig struct {
-	Addr       Addr
-	Engine     Engine
-	SrvOpts    []grpc.ServerOption
-	CodeAssert CodeAssert
+	Addr            Addr
+	Engine          Engine
+	EngineV2        EngineV2
+	SrvOpts         []grpc.ServerOption
+	CodeAssert      CodeAssert
+	Auth            Authenticator
+	Metrics         Metrics
+	Buffer          int
+	Expired         ExpiredHandler
+	DeadLetter      DeadLetterSink
+	MaxAttempts     int
+	Compressions    []string
+	MinCompressSize int
+	MaxJobSize      int
+	MaxSubmitJobs   int
+	Exporter        SpanExporter
+	DrainTimeout    time.Duration
 }
 
+// defaultStreamBuffer is the Buffer if it is not set
+const defaultStreamBuffer = 64
+
+// defaultMaxSubmitJobs is the MaxSubmitJobs if it is not set
+const defaultMaxSubmitJobs = 1000
+
+// defaultDrainTimeout is the DrainTimeout if it is not set
+const defaultDrainTimeout = 2 * time.Second
+
 // CodeAssert asserts if code is valid
 type CodeAssert = func(code Code) bool
 
 // InitServer init server
 func InitServer(cfg *ServerConfig) (*Server, error) {
-	return &Server{
-		cfg:   cfg,
-		close: make(Done),
-	}, nil
+	engine := cfg.EngineV2
+	if engine == nil {
+		if cfg.Engine == nil {
+			return nil, errors.New("no engine")
+		}
+		engine = AdaptEngine(cfg.Engine)
+	}
+
+	s := &Server{
+		cfg:     cfg,
+		engine:  engine,
+		close:   make(Done),
+		metrics: metricsOrNop(cfg.Metrics),
+		streams: make(map[string]*downstream),
+	}
+	s.router = newRouter(func(j *Job) {
+		putDeadLetter(s.cfg.DeadLetter, s.metrics, j, "unrouted", "too many jobs no subscriber matches")
+	})
+	return s, nil
 }
 
 // Run runs the server
 func (s *Server) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext runs the server until ctx is canceled,
+// then it closes the server and stops serving when streams are drained
+func (s *Server) RunContext(ctx context.Context) error {
 	// start this server
 	lis, err := net.Listen("tcp", s.cfg.Addr)
 	if err != nil {
@@ -55,14 +130,36 @@ func (s *Server) Run() error {
 	ss := s
 	gsrv := grpc.NewServer(s.cfg.SrvOpts...)
 	job.RegisterServiceServer(gsrv, ss)
+	if s.admin != nil {
+		admin.RegisterAdminServer(gsrv, s.admin)
+	}
 	log.Infof("start listening %v", s.cfg.Addr)
-	return gsrv.Serve(lis)
+
+	served := make(chan struct{})
+	defer close(served)
+	go func() {
+		select {
+		case <-ctx.Done():
+			<-s.Close()
+			gsrv.Stop()
+		case <-served:
+		}
+	}()
+
+	err = gsrv.Serve(lis)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 // implement jobServer
 
 // Ask implement jobServiceServer interface
-func (s *Server) Ask(pass *job.Passphrase, stream job.Service_AskServer) error {
+// jobs are held until the downstream acknowledges them,
+// nacked jobs and jobs unacked when the stream ends are redelivered.
+// jobs from engine not match the topics of the stream are routed to matched streams
+func (s *Server) Ask(stream job.Service_AskServer) (err error) {
 
 	log.Infof("recieve connection")
 
@@ -70,53 +167,177 @@ func (s *Server) Ask(pass *job.Passphrase, stream job.Service_AskServer) error {
 		return status.Errorf(codes.Aborted, "server is closing")
 	}
 
-	if !s.cfg.CodeAssert(pass.GetCode()) {
-		return status.Errorf(codes.InvalidArgument, "wrong passcode %v", pass.GetCode())
+	fb, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	pass := fb.GetPassphrase()
+	id, err := s.authenticate(stream.Context(), pass.GetCode())
+	if err != nil {
+		log.Errorf("authenticate fail, error: %v", err)
+		return err
 	}
 
 	s.wg.Add(1)
 	defer s.wg.Done()
 
-	sig := make(chan Signal) // TODO: make(chan error)
-	outbound, err := s.cfg.Engine.Register(sig)
+	// ctx of the engine registration is canceled when the stream ends
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	addr := peerAddr(stream.Context())
+	sig := make(chan Signal)
+	outbound, err := s.engine.Register(ctx, RegisterInfo{
+		Identity: id,
+		Peer:     addr,
+		Topics:   pass.GetTopics(),
+		Signal:   sig,
+	})
 	if err != nil {
 		log.Errorf("engine register error: %v", err)
-		return status.Errorf(codes.Unavailable, err.Error())
+		return status.Error(codes.Unavailable, err.Error())
 	}
 
-	for j := range outbound {
-		gj := toGRPCJob(j)
-		err := stream.Send(gj) // TODO: retry?
-		if err != nil {
-			log.Errorf("err: %v", err)
-			sig <- Signal{
-				Err: err,
+	// cause is the error ends the stream
+	var cause error
+	n := newSignaler(sig, s.metrics)
+	defer func() {
+		n.emit(Signal{
+			Type:   SignalDisconnected,
+			Err:    cause,
+			Status: status.Convert(err),
+		})
+		n.close()
+	}()
+	n.emit(Signal{
+		Type:     SignalConnected,
+		Peer:     addr,
+		Code:     pass.GetCode(),
+		Identity: id,
+	})
+
+	d := &downstream{
+		id:          newJobID(),
+		identity:    id,
+		connectedAt: time.Now(),
+		kick:        make(chan string, 1),
+		stream:      stream,
+		peer:        peerHost(addr),
+		sub:         s.router.subscribe(pass.GetTopics()),
+		unacked:     make(map[string]*Job),
+		credit:      -1,
+		signal:      n,
+		metrics:     s.metrics,
+		expired:     s.cfg.Expired,
+		compression: negotiate(pass.GetCompressions(), s.cfg.Compressions),
+		minCompress: s.minCompressSize(),
+		exporter:    exporterOrNop(s.cfg.Exporter),
+	}
+	if d.compression != "" {
+		log.WithFields(log.Fields{
+			"peer":        addr,
+			"compression": d.compression,
+		}).Info("compress jobs")
+	}
+	s.metrics.StreamOpened(d.peer)
+	defer s.metrics.StreamClosed(d.peer)
+	s.attach(d)
+	defer s.detach(d)
+	d.grant(fb.GetCredit())
+	defer func() {
+		s.requeue(d, cause)
+	}()
+
+	fbs, recvErr := recvFeedback(stream)
+
+loop:
+	for {
+		jobs, ready := s.flow(d, outbound)
+
+		select {
+		case j, ok := <-jobs:
+			if !ok {
+				return status.Error(codes.Unavailable, "service closed")
 			}
-			return status.Error(codes.Unavailable, err.Error())
-		}
 
-		if s.shouldClose() {
+			s.take(d, j)
+		case <-ready:
+			j := d.sub.queue.pop()
+			if j == nil {
+				continue
+			}
+
+			err := d.send(j) // TODO: retry?
+			if err != nil {
+				log.Errorf("err: %v", err)
+				cause = err
+				return status.Error(codes.Unavailable, err.Error())
+			}
+		case fb, ok := <-fbs:
+			if !ok {
+				err := <-recvErr
+				log.Infof("downstream closed, error: %v", err)
+				if err != io.EOF {
+					cause = err
+				}
+				return status.Error(codes.Canceled, "downstream closed")
+			}
+
+			s.feedback(d, fb)
+		case reason := <-d.kick:
+			log.Infof("disconnect downstream %v, reason: %v", d.id, reason)
+			cause = errors.New("disconnected by admin: " + reason)
+			return status.Error(codes.Aborted, reason)
+		case <-s.close:
 			log.Infof("server closing")
-			close(sig)
-			break
+			n.emit(Signal{
+				Type: SignalDraining,
+			})
+			break loop
 		}
 	}
 
-	// clear all left jobs
-	wait := time.After(2 * time.Second)
+	// flush left jobs until all are acked
+	wait := time.After(s.drainTimeout())
 	for {
+		if d.flushed(outbound) {
+			log.WithFields(log.Fields{
+				"id": d.id,
+			}).Info("stream drained")
+			return status.Error(codes.Unavailable, "service closed")
+		}
+
+		jobs, ready := s.flow(d, outbound)
+
 		select {
-		case j, ok := <-outbound:
+		case j, ok := <-jobs:
 			if !ok {
 				return status.Error(codes.Unavailable, "service closed")
 			}
 
-			gj := toGRPCJob(j)
-			err := stream.Send(gj)
+			s.take(d, j)
+		case <-ready:
+			j := d.sub.queue.pop()
+			if j == nil {
+				continue
+			}
+
+			err := d.send(j)
 			if err != nil {
 				log.Errorf("err: %v", err)
+				cause = err
 				return status.Error(codes.Unavailable, err.Error())
 			}
+		case fb, ok := <-fbs:
+			if !ok {
+				return status.Error(codes.Canceled, "downstream closed")
+			}
+
+			s.feedback(d, fb)
+		case reason := <-d.kick:
+			cause = errors.New("disconnected by admin: " + reason)
+			return status.Error(codes.Aborted, reason)
 		case <-wait:
 			log.Infof("time up")
 			return status.Error(codes.Unavailable, "service closed")
@@ -124,6 +345,374 @@ func (s *Server) Ask(pass *job.Passphrase, stream job.Service_AskServer) error {
 	}
 }
 
+// flow returns the channels the stream can take jobs from,
+// it stops taking jobs from engine when queue of the stream is full
+// and stops sending jobs when downstream has no credit
+func (s *Server) flow(d *downstream, outbound <-chan *Job) (<-chan *Job, chan struct{}) {
+	jobs, ready := outbound, d.sub.queue.ready
+	if d.sub.queue.len() >= s.buffer() {
+		jobs = nil
+	}
+	if d.credit == 0 {
+		ready = nil
+	}
+	return jobs, ready
+}
+
+// take queues the job from engine to the stream,
+// the job with highest priority in queue is sent first.
+// The job not match the topics of the stream is routed to a matched stream,
+// it is put to DeadLetter if no stream matches
+func (s *Server) take(d *downstream, j *Job) {
+	if !d.sub.match(j) {
+		if !s.router.forward(j) {
+			putDeadLetter(s.cfg.DeadLetter, s.metrics, j, "unmatched", "no topic of any stream matches")
+		}
+		return
+	}
+
+	d.sub.queue.push(j)
+}
+
+func (s *Server) minCompressSize() int {
+	if s.cfg.MinCompressSize < 1 {
+		return defaultMinCompressSize
+	}
+	return s.cfg.MinCompressSize
+}
+
+func (s *Server) drainTimeout() time.Duration {
+	if s.cfg.DrainTimeout <= 0 {
+		return defaultDrainTimeout
+	}
+	return s.cfg.DrainTimeout
+}
+
+func (s *Server) buffer() int {
+	if s.cfg.Buffer < 1 {
+		return defaultStreamBuffer
+	}
+	return s.cfg.Buffer
+}
+
+// authenticate uses Auth if it is set, otherwise CodeAssert
+func (s *Server) authenticate(ctx context.Context, code Code) (*Identity, error) {
+	if s.cfg.Auth == nil {
+		if !s.cfg.CodeAssert(code) {
+			return nil, status.Errorf(codes.InvalidArgument, "wrong passcode %v", code)
+		}
+
+		return &Identity{
+			Name:   passcodeName(code),
+			Method: "passcode",
+		}, nil
+	}
+
+	id, err := s.cfg.Auth.Authenticate(newAuthInfo(ctx, code))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return id, nil
+}
+
+// passcodeName names the downstream by the fingerprint of its passcode,
+// so the passcode is not shown in logs, signals and admin
+func passcodeName(code Code) string {
+	if code == "" {
+		return "passcode"
+	}
+	sum := sha256.Sum256([]byte(code))
+	return "passcode:" + hex.EncodeToString(sum[:4])
+}
+
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// feedback handles the ack and credit from downstream
+func (s *Server) feedback(d *downstream, fb *job.Feedback) {
+	s.ack(d, fb.GetAck())
+	d.grant(fb.GetCredit())
+}
+
+func (s *Server) ack(d *downstream, a *job.Ack) {
+	if a == nil {
+		return
+	}
+
+	j, ok := d.ack(a.GetId())
+	if !ok {
+		log.Warnf("ack unknown job %v", a.GetId())
+		return
+	}
+
+	if !a.GetNack() {
+		d.signal.emit(Signal{
+			Type:  SignalAcked,
+			JobID: a.GetId(),
+		})
+		return
+	}
+
+	d.signal.emit(Signal{
+		Type:   SignalNacked,
+		JobID:  a.GetId(),
+		Reason: a.GetReason(),
+	})
+	if s.exhausted(j) {
+		putDeadLetter(s.cfg.DeadLetter, s.metrics, j, "nacked", a.GetReason())
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"id":     a.GetId(),
+		"reason": a.GetReason(),
+	}).Info("job nacked, redeliver")
+	s.router.route(j)
+}
+
+// requeue unsubscribes the downstream and routes its unacked jobs to others,
+// cause is the error ends the stream
+func (s *Server) requeue(d *downstream, cause error) {
+	s.router.unsubscribe(d.sub)
+	if len(d.unacked) == 0 {
+		return
+	}
+
+	lastErr := ""
+	if cause != nil {
+		lastErr = cause.Error()
+	}
+
+	jobs := make([]*Job, 0, len(d.unacked))
+	for _, j := range d.unacked {
+		if s.exhausted(j) {
+			putDeadLetter(s.cfg.DeadLetter, s.metrics, j, "stream closed", lastErr)
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	log.Infof("redeliver %v unacked jobs", len(jobs))
+	s.router.route(jobs...)
+}
+
+// exhausted returns true if the job is sent MaxAttempts times
+func (s *Server) exhausted(j *Job) bool {
+	return s.cfg.MaxAttempts > 0 && j.attempts >= s.cfg.MaxAttempts
+}
+
+// attach keeps the downstream for admin
+func (s *Server) attach(d *downstream) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	s.streams[d.id] = d
+}
+
+func (s *Server) detach(d *downstream) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	delete(s.streams, d.id)
+}
+
+// downstreams returns the connected downstreams
+func (s *Server) downstreams() []*downstream {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+
+	ds := make([]*downstream, 0, len(s.streams))
+	for _, d := range s.streams {
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(a, b int) bool {
+		return ds[a].connectedAt.Before(ds[b].connectedAt)
+	})
+	return ds
+}
+
+// disconnect ends the stream of the downstream, it returns false if no such downstream
+func (s *Server) disconnect(id, reason string) bool {
+	s.streamsMu.Lock()
+	d, ok := s.streams[id]
+	s.streamsMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	select {
+	case d.kick <- reason:
+	default:
+	}
+	return true
+}
+
+// downstream is the state of an Ask stream
+// credit is the number of jobs downstream can take, negative means unlimited.
+// sent, unackedN and creditN are read by admin
+type downstream struct {
+	id          string
+	identity    *Identity
+	connectedAt time.Time
+	kick        chan string
+	sent        uint64
+	unackedN    int64
+	creditN     int64
+
+	stream      job.Service_AskServer
+	peer        string
+	sub         *subscriber
+	unacked     map[string]*Job
+	credit      int64
+	signal      *signaler
+	metrics     Metrics
+	expired     ExpiredHandler
+	compression string
+	minCompress int
+	exporter    SpanExporter
+}
+
+// send sends the job to downstream and holds it in unacked,
+// the job past the deadline is not sent
+func (d *downstream) send(j *Job) error {
+	if j.Expired() {
+		d.expire(j)
+		return nil
+	}
+
+	// engine may send the same job to other streams, so count attempts on a copy
+	cp := *j
+	if cp.ID == "" {
+		cp.ID = newJobID()
+	}
+	cp.attempts++
+	j = &cp
+
+	d.unacked[j.ID] = j
+	if d.credit > 0 {
+		d.credit--
+		if d.credit == 0 {
+			d.signal.emit(Signal{
+				Type: SignalPaused,
+			})
+		}
+	}
+	d.stat()
+
+	// the job sent is in the span, so the span of next hop is its child
+	span := startSpan("linkage.send", j.Trace, map[string]string{
+		"job.id": j.ID,
+		"peer":   d.peer,
+	})
+	sent := *j
+	sent.Trace = span.Context
+
+	gj := toGRPCJob(&sent)
+	if d.compression != "" {
+		err := compressJob(gj, d.compression, d.minCompress)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id": j.ID,
+			}).Errorf("compress job fail, send it uncompressed, error: %v", err)
+			gj = toGRPCJob(&sent)
+		}
+	}
+
+	start := time.Now()
+	err := d.stream.Send(gj)
+	span.end(d.exporter, err)
+	if err != nil {
+		return err
+	}
+
+	atomic.AddUint64(&d.sent, 1)
+	d.metrics.JobSent(d.peer, time.Since(start))
+	return nil
+}
+
+// flushed returns true if no job is waiting for the stream or its ack
+func (d *downstream) flushed(outbound <-chan *Job) bool {
+	return len(outbound) == 0 && d.sub.queue.len() == 0 && len(d.unacked) == 0
+}
+
+// stat updates the stats read by admin
+func (d *downstream) stat() {
+	atomic.StoreInt64(&d.unackedN, int64(len(d.unacked)))
+	atomic.StoreInt64(&d.creditN, d.credit)
+}
+
+// expire drops the job or diverts it to expired handler
+func (d *downstream) expire(j *Job) {
+	log.WithFields(log.Fields{
+		"id":       j.ID,
+		"deadline": j.Deadline,
+	}).Warn("job expired")
+	d.metrics.JobDropped("expired")
+	d.signal.emit(Signal{
+		Type:   SignalExpired,
+		JobID:  j.ID,
+		Reason: "deadline exceeded",
+	})
+	if d.expired != nil {
+		d.expired(j)
+	}
+}
+
+func (d *downstream) ack(id string) (*Job, bool) {
+	j, ok := d.unacked[id]
+	if ok {
+		delete(d.unacked, id)
+		d.stat()
+	}
+	return j, ok
+}
+
+// grant enables flow control once the downstream gives credit
+func (d *downstream) grant(credit uint32) {
+	if credit == 0 {
+		return
+	}
+
+	if d.credit == 0 {
+		d.signal.emit(Signal{
+			Type: SignalResumed,
+		})
+	}
+	if d.credit < 0 {
+		d.credit = 0
+	}
+	d.credit += int64(credit)
+	d.stat()
+}
+
+// recvFeedback receives feedback from downstream until the stream ends,
+// then closes the feedback channel and sends the error to error channel
+func recvFeedback(stream job.Service_AskServer) (<-chan *job.Feedback, <-chan error) {
+	fbs := make(chan *job.Feedback)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(fbs)
+		for {
+			fb, err := stream.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			select {
+			case fbs <- fb:
+			case <-stream.Context().Done():
+				errc <- stream.Context().Err()
+				return
+			}
+		}
+	}()
+
+	return fbs, errc
+}
+
 func (s *Server) shouldClose() bool {
 	select {
 	case <-s.close:
@@ -135,7 +724,9 @@ func (s *Server) shouldClose() bool {
 
 // Close close the server
 func (s *Server) Close() Done {
-	close(s.close)
+	s.closeOnce.Do(func() {
+		close(s.close)
+	})
 
 	done := make(Done)
 	go func() {
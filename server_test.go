@@ -0,0 +1,230 @@
+package linkage
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// testEngine sends n jobs to downstreams and forwards the signals to sigs
+type testEngine struct {
+	n    int
+	sigs chan Signal
+}
+
+func (e *testEngine) Start(<-chan *Job) error { return nil }
+
+func (e *testEngine) Register(sig chan Signal) (<-chan *Job, error) {
+	jobs := make(chan *Job, e.n)
+	for i := 0; i < e.n; i++ {
+		jobs <- CreateJob(fmt.Sprint(i), nil)
+	}
+	go func() {
+		for s := range sig {
+			if e.sigs != nil {
+				e.sigs <- s
+			}
+		}
+	}()
+	return jobs, nil
+}
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// serveTest runs a server of e on a free port and returns a client
+// of info streaming from it
+func serveTest(t *testing.T, e Engine, info DialInfo) *Client {
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:       addr,
+		Engine:     e,
+		CodeAssert: func(Code) bool { return true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	t.Cleanup(func() { <-srv.Close() })
+
+	info.Addr = addr
+	info.Opts = []grpc.DialOption{grpc.WithInsecure()}
+	return dialTest(t, &info)
+}
+
+// dialTest builds the stream of info, it retries while the server is starting
+func dialTest(t *testing.T, info *DialInfo) *Client {
+	var err error
+	for i := 0; i < 50; i++ {
+		var c *Client
+		c, err = InitClient(info)
+		if err == nil {
+			if err = c.BuildStream(); err == nil {
+				return c
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal(err)
+	return nil
+}
+
+// askTimeout asks for a job, it returns nil if no job is sent before timeout
+func askTimeout(t *testing.T, c *Client, timeout time.Duration) *Job {
+	type result struct {
+		j   *Job
+		err error
+	}
+	got := make(chan result, 1)
+	go func() {
+		j, err := c.Ask()
+		got <- result{j, err}
+	}()
+	select {
+	case r := <-got:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		return r.j
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestServerRedeliver(t *testing.T) {
+	cases := []struct {
+		name string
+		nack bool
+		want int
+	}{
+		{"nack", true, 1},
+		{"ack", false, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := serveTest(t, &testEngine{n: 2}, DialInfo{})
+			defer c.Close()
+
+			first := askTimeout(t, c, time.Second)
+			if first == nil {
+				t.Fatal("no job sent")
+			}
+			if tc.nack {
+				c.Nack(first.ID, "later")
+			} else {
+				c.Ack(first.ID)
+			}
+
+			seen := 0
+			for {
+				j := askTimeout(t, c, 300*time.Millisecond)
+				if j == nil {
+					break
+				}
+				if j.ID == first.ID {
+					seen++
+				}
+				c.Ack(j.ID)
+			}
+			if seen != tc.want {
+				t.Errorf("job sent %v times again, want %v", seen, tc.want)
+			}
+		})
+	}
+}
+
+func TestServerRedeliverUnacked(t *testing.T) {
+	addr := freeAddr(t)
+	srv, err := InitServer(&ServerConfig{
+		Addr:       addr,
+		Engine:     &testEngine{n: 1},
+		CodeAssert: func(Code) bool { return true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Run()
+	defer func() { <-srv.Close() }()
+
+	info := &DialInfo{
+		Addr: addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	}
+	c := dialTest(t, info)
+	j := askTimeout(t, c, time.Second)
+	if j == nil {
+		t.Fatal("no job sent")
+	}
+	// the stream ends before the job is acked
+	c.Close()
+
+	// the engine sends a new job to the new stream too
+	c = dialTest(t, info)
+	defer c.Close()
+	for {
+		again := askTimeout(t, c, 2*time.Second)
+		if again == nil {
+			t.Fatalf("unacked job %v not redelivered", j.ID)
+		}
+		c.Ack(again.ID)
+		if again.ID == j.ID {
+			return
+		}
+	}
+}
+
+func TestServerWindow(t *testing.T) {
+	cases := []struct {
+		name   string
+		window int
+	}{
+		{"window 1", 1},
+		{"window 3", 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := serveTest(t, &testEngine{n: tc.window + 1}, DialInfo{Window: tc.window})
+			defer c.Close()
+
+			var jobs []*Job
+			for i := 0; i < tc.window; i++ {
+				j := askTimeout(t, c, time.Second)
+				if j == nil {
+					t.Fatalf("%v of %v jobs sent", i, tc.window)
+				}
+				jobs = append(jobs, j)
+			}
+
+			got := make(chan *Job, 1)
+			go func() {
+				j, _ := c.Ask()
+				got <- j
+			}()
+			select {
+			case <-got:
+				t.Fatal("job sent beyond the window")
+			case <-time.After(300 * time.Millisecond):
+			}
+
+			c.Ack(jobs[0].ID)
+			select {
+			case j := <-got:
+				if j == nil {
+					t.Fatal("stream closed")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("not resumed after ack")
+			}
+		})
+	}
+}
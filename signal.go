@@ -0,0 +1,124 @@
+package linkage
+
+import (
+	"sync"
+	"time"
+)
+
+// maxSignals is the max number of signals queued for engine,
+// new signals are dropped when it is full except SignalDisconnected
+const maxSignals = 256
+
+// signalCloseTimeout is how long the signals left are waited to be taken after close
+const signalCloseTimeout = 5 * time.Second
+
+// signaler sends signals to engine in order without blocking the sender,
+// the signal channel is closed after all signals are taken, or after
+// signalCloseTimeout since close if engine stops taking them
+type signaler struct {
+	sig     chan Signal
+	metrics Metrics
+	mu      sync.Mutex
+	queue   []Signal
+	closed  bool
+	ready   chan struct{}
+	done    chan struct{}
+}
+
+func newSignaler(sig chan Signal, m Metrics) *signaler {
+	n := &signaler{
+		sig:     sig,
+		metrics: metricsOrNop(m),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// emit queues the signal, it is dropped if the queue is full or the signaler is closed
+func (n *signaler) emit(s Signal) {
+	n.mu.Lock()
+	drop := n.closed || (len(n.queue) >= maxSignals && s.Type != SignalDisconnected)
+	if !drop {
+		n.queue = append(n.queue, s)
+	}
+	n.mu.Unlock()
+
+	if drop {
+		n.metrics.SignalDropped(s.Type.String())
+		return
+	}
+	n.notify()
+}
+
+// close closes the signal channel after the queued signals are taken
+func (n *signaler) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return
+	}
+	n.closed = true
+	close(n.done)
+}
+
+func (n *signaler) notify() {
+	select {
+	case n.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (n *signaler) run() {
+	done := n.done
+	var timeup <-chan time.Time
+	for {
+		n.mu.Lock()
+		if len(n.queue) == 0 {
+			closed := n.closed
+			n.mu.Unlock()
+			if closed {
+				close(n.sig)
+				return
+			}
+
+			select {
+			case <-n.ready:
+			case <-done:
+				done = nil
+			}
+			continue
+		}
+		s := n.queue[0]
+		n.mu.Unlock()
+
+		select {
+		case n.sig <- s:
+			n.mu.Lock()
+			n.queue = n.queue[1:]
+			n.mu.Unlock()
+		case <-done:
+			// the stream ended, engine has a while to take the signals left
+			done = nil
+			timeup = time.After(signalCloseTimeout)
+		case <-timeup:
+			n.dropLeft()
+			close(n.sig)
+			return
+		}
+	}
+}
+
+// dropLeft drops the signals engine does not take
+func (n *signaler) dropLeft() {
+	n.mu.Lock()
+	left := n.queue
+	n.queue = nil
+	n.mu.Unlock()
+
+	for _, s := range left {
+		n.metrics.SignalDropped(s.Type.String())
+	}
+}
@@ -0,0 +1,112 @@
+package linkage
+
+import (
+	"testing"
+	"time"
+)
+
+// takeSignals takes signals until the channel is closed
+func takeSignals(t *testing.T, sig chan Signal) []SignalType {
+	var got []SignalType
+	for {
+		select {
+		case s, ok := <-sig:
+			if !ok {
+				return got
+			}
+			got = append(got, s.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("signal channel not closed, got %v", got)
+		}
+	}
+}
+
+func TestSignalerOrder(t *testing.T) {
+	sig := make(chan Signal)
+	n := newSignaler(sig, nil)
+
+	want := []SignalType{SignalConnected, SignalPaused, SignalResumed, SignalDisconnected}
+	// emit never blocks even nobody takes the signals
+	for _, typ := range want {
+		n.emit(Signal{Type: typ})
+	}
+	n.close()
+
+	got := takeSignals(t, sig)
+	if len(got) != len(want) {
+		t.Fatalf("got signals %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got signals %v, want %v", got, want)
+		}
+	}
+}
+
+// droppedMetrics counts the signals dropped
+type droppedMetrics struct {
+	nopMetrics
+	dropped chan string
+}
+
+func (m *droppedMetrics) SignalDropped(signal string) {
+	m.dropped <- signal
+}
+
+func TestSignalerBounded(t *testing.T) {
+	sig := make(chan Signal)
+	m := &droppedMetrics{dropped: make(chan string, maxSignals)}
+	n := newSignaler(sig, m)
+
+	// nobody takes the signals, so the ones beyond maxSignals are dropped
+	for i := 0; i < maxSignals+2; i++ {
+		n.emit(Signal{Type: SignalAcked})
+	}
+	n.emit(Signal{Type: SignalDisconnected})
+	n.close()
+
+	// SignalDisconnected is never dropped
+	if len(m.dropped) != 2 {
+		t.Fatalf("%v signals dropped, want 2", len(m.dropped))
+	}
+	got := takeSignals(t, sig)
+	if len(got) != maxSignals+1 || got[len(got)-1] != SignalDisconnected {
+		t.Fatalf("got %v signals ends with %v, want %v ends with disconnected",
+			len(got), got[len(got)-1], maxSignals+1)
+	}
+}
+
+func TestServerSignals(t *testing.T) {
+	e := &testEngine{n: 2, sigs: make(chan Signal, 16)}
+	c := serveTest(t, e, DialInfo{})
+
+	first := askTimeout(t, c, time.Second)
+	second := askTimeout(t, c, time.Second)
+	if first == nil || second == nil {
+		t.Fatal("no job sent")
+	}
+	c.Ack(first.ID)
+	c.Nack(second.ID, "later")
+	if askTimeout(t, c, time.Second) == nil {
+		t.Fatal("nacked job not sent again")
+	}
+	c.Close()
+
+	want := []Signal{
+		{Type: SignalConnected},
+		{Type: SignalAcked, JobID: first.ID},
+		{Type: SignalNacked, JobID: second.ID, Reason: "later"},
+		{Type: SignalDisconnected},
+	}
+	for _, w := range want {
+		select {
+		case s := <-e.sigs:
+			if s.Type != w.Type || s.JobID != w.JobID || s.Reason != w.Reason {
+				t.Fatalf("got signal %v %v %v, want %v %v %v",
+					s.Type, s.JobID, s.Reason, w.Type, w.JobID, w.Reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("signal %v not sent", w.Type)
+		}
+	}
+}
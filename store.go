@@ -0,0 +1,275 @@
+package linkage
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// JobStore keeps jobs until the engine finishes them,
+// so jobs in flight are not lost when the service restarts
+type JobStore interface {
+	// Append stores the job before it is sent to engine
+	Append(j *Job) error
+	// Done marks the job as finished
+	Done(id string) error
+	// Unfinished returns jobs not done yet in append order
+	Unfinished() ([]*Job, error)
+	// Close closes the store
+	Close() error
+}
+
+// FileStore is an append-only file JobStore.
+// Each line in the file is a json record of a new job or a done job id,
+// the file is truncated once all jobs in it are done, and rewritten with
+// the unfinished jobs only once compactThreshold jobs in it are done
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	seq     uint64
+	pending map[string]*storedJob
+	done    int
+}
+
+// compactThreshold is the number of done records in the file to compact it
+const compactThreshold = 1024
+
+type storedJob struct {
+	seq uint64
+	job *Job
+}
+
+type storeRecord struct {
+	Job  *Job   `json:"job,omitempty"`
+	Done string `json:"done,omitempty"`
+}
+
+// OpenFileStore opens or creates the store file at path
+// and loads the unfinished jobs in it
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		path:    path,
+		f:       f,
+		pending: make(map[string]*storedJob),
+	}
+	err = s.load()
+	if err == nil && s.done >= compactThreshold {
+		err = s.compact()
+	}
+	if err != nil {
+		s.f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	dec := json.NewDecoder(s.f)
+	for {
+		var r storeRecord
+		offset := dec.InputOffset()
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// the last record is broken if the service crashed when writing it
+			log.WithFields(log.Fields{
+				"file":   s.f.Name(),
+				"offset": offset,
+			}).Warnf("drop broken record, error: %v", err)
+			return s.truncate(offset)
+		}
+
+		s.apply(&r)
+	}
+}
+
+// truncate drops the file from offset, offset is the end of the last good record
+// so the newline after it is written back for the next record to start a new line
+func (s *FileStore) truncate(offset int64) error {
+	err := s.f.Truncate(offset)
+	if err != nil || offset == 0 {
+		return err
+	}
+
+	_, err = s.f.Write([]byte{'\n'})
+	return err
+}
+
+func (s *FileStore) apply(r *storeRecord) {
+	if r.Job != nil {
+		s.seq++
+		s.pending[r.Job.ID] = &storedJob{
+			seq: s.seq,
+			job: r.Job,
+		}
+	}
+	if r.Done != "" {
+		delete(s.pending, r.Done)
+		s.done++
+	}
+}
+
+func (s *FileStore) write(r *storeRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.f.Write(append(b, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Append implement JobStore interface
+func (s *FileStore) Append(j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &storeRecord{
+		Job: j,
+	}
+	err := s.write(r)
+	if err != nil {
+		return err
+	}
+
+	s.apply(r)
+	return nil
+}
+
+// Done implement JobStore interface
+func (s *FileStore) Done(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pending[id]; !ok {
+		return nil
+	}
+
+	r := &storeRecord{
+		Done: id,
+	}
+	if len(s.pending) == 1 {
+		// the last unfinished job, no record worth to keep
+		s.apply(r)
+		s.done = 0
+		return s.f.Truncate(0)
+	}
+
+	err := s.write(r)
+	if err != nil {
+		return err
+	}
+
+	s.apply(r)
+	if s.done >= compactThreshold {
+		// the job is done anyway, compact again on next Done
+		err = s.compact()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"file": s.path,
+			}).Errorf("compact store fail, error: %v", err)
+		}
+	}
+	return nil
+}
+
+// compact rewrites the unfinished jobs to a new file which replaces the store file,
+// the store file is intact if it fails
+func (s *FileStore) compact() error {
+	tmp := s.path + ".compact"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, sj := range s.sorted() {
+		b, err := json.Marshal(&storeRecord{
+			Job: sj.job,
+		})
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+		w.Write(append(b, '\n'))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	nf, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.f.Close()
+	s.f = nf
+	s.done = 0
+	log.WithFields(log.Fields{
+		"file":    s.path,
+		"pending": len(s.pending),
+	}).Info("store compacted")
+	return nil
+}
+
+// Unfinished implement JobStore interface
+func (s *FileStore) Unfinished() ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sjs := s.sorted()
+	jobs := make([]*Job, len(sjs))
+	for i, sj := range sjs {
+		jobs[i] = sj.job
+	}
+	return jobs, nil
+}
+
+// sorted returns the unfinished jobs in append order
+func (s *FileStore) sorted() []*storedJob {
+	sjs := make([]*storedJob, 0, len(s.pending))
+	for _, sj := range s.pending {
+		sjs = append(sjs, sj)
+	}
+	sort.Slice(sjs, func(a, b int) bool {
+		return sjs[a].seq < sjs[b].seq
+	})
+	return sjs
+}
+
+// Close implement JobStore interface
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
@@ -0,0 +1,238 @@
+package linkage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func tempStorePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "linkage-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "jobs.log"), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func unfinishedIDs(t *testing.T, s *FileStore) []string {
+	jobs, err := s.Unfinished()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+	for _, j := range jobs {
+		ids = append(ids, j.ID)
+	}
+	return ids
+}
+
+func countLines(t *testing.T, path string) int {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(b, []byte("\n"))
+}
+
+func TestFileStore(t *testing.T) {
+	cases := []struct {
+		name   string
+		append []string
+		done   []string
+		want   []string
+		lines  int
+	}{
+		{
+			name:   "append order",
+			append: []string{"c", "a", "b"},
+			want:   []string{"c", "a", "b"},
+			lines:  3,
+		},
+		{
+			name:   "done",
+			append: []string{"a", "b", "c"},
+			done:   []string{"b"},
+			want:   []string{"a", "c"},
+			lines:  4,
+		},
+		{
+			name:   "done unknown",
+			append: []string{"a"},
+			done:   []string{"x", "a", "a"},
+			want:   []string{},
+			lines:  0,
+		},
+		{
+			name:   "truncated when all done",
+			append: []string{"a", "b"},
+			done:   []string{"a", "b"},
+			want:   []string{},
+			lines:  0,
+		},
+	}
+
+	for _, c := range cases {
+		path, clean := tempStorePath(t)
+
+		s, err := OpenFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range c.append {
+			err = s.Append(&Job{ID: id, Payload: id})
+			if err != nil {
+				t.Fatalf("%v: append fail, error: %v", c.name, err)
+			}
+		}
+		for _, id := range c.done {
+			err = s.Done(id)
+			if err != nil {
+				t.Fatalf("%v: done fail, error: %v", c.name, err)
+			}
+		}
+		if got := unfinishedIDs(t, s); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: unfinished %v, want %v", c.name, got, c.want)
+		}
+		s.Close()
+
+		if got := countLines(t, path); got != c.lines {
+			t.Errorf("%v: %v lines in file, want %v", c.name, got, c.lines)
+		}
+
+		// the jobs are loaded again after restart
+		s, err = OpenFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := unfinishedIDs(t, s); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: unfinished %v after reopen, want %v", c.name, got, c.want)
+		}
+		s.Close()
+		clean()
+	}
+}
+
+func TestFileStoreBrokenRecord(t *testing.T) {
+	path, clean := tempStorePath(t)
+	defer clean()
+
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Append(&Job{ID: "a"})
+	s.Close()
+
+	// crashed when writing the last record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"job":{"id":"b"`)
+	f.Close()
+
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := unfinishedIDs(t, s); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("unfinished %v, want [a]", got)
+	}
+
+	s.Append(&Job{ID: "c"})
+	// the record starts a new line after the last good one
+	if got := countLines(t, path); got != 2 {
+		t.Fatalf("%v lines in file, want 2", got)
+	}
+	s.Close()
+
+	// the record after the broken one is loaded after restart
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := unfinishedIDs(t, s); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Fatalf("unfinished %v after reopen, want [a c]", got)
+	}
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	path, clean := tempStorePath(t)
+	defer clean()
+
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first job is never done, so the file is never truncated
+	s.Append(&Job{ID: "keep"})
+	for i := 0; i < compactThreshold; i++ {
+		id := strconv.Itoa(i)
+		s.Append(&Job{ID: id})
+		s.Done(id)
+	}
+
+	if got := countLines(t, path); got != 1 {
+		t.Fatalf("%v lines in file after compaction, want 1", got)
+	}
+
+	// the store keeps appending to the compacted file
+	s.Append(&Job{ID: "next"})
+	s.Close()
+
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := unfinishedIDs(t, s); !reflect.DeepEqual(got, []string{"keep", "next"}) {
+		t.Fatalf("unfinished %v, want [keep next]", got)
+	}
+}
+
+func TestFeedClosing(t *testing.T) {
+	path, clean := tempStorePath(t)
+	defer clean()
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	l, err := InitLinkage(freeAddr(t), newCollectEngine(), nil, nil, nil, nil, WithJobStore(store), WithIncomeBuffer(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = l.feed(&Job{ID: "queued"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the job waiting for a free slot when linkage closes is not left in store
+	fed := make(chan error, 1)
+	go func() { fed <- l.feed(&Job{ID: "waiting"}) }()
+	time.Sleep(50 * time.Millisecond)
+	close(l.closing)
+	if err := <-fed; err != errClosing {
+		t.Fatalf("got error %v, want %v", err, errClosing)
+	}
+
+	// the job comes after linkage closed is not stored
+	if err := l.feed(&Job{ID: "late"}); err != errClosing {
+		t.Fatalf("got error %v, want %v", err, errClosing)
+	}
+
+	if ids := unfinishedIDs(t, store); !reflect.DeepEqual(ids, []string{"queued"}) {
+		t.Fatalf("unfinished jobs %v, want [queued]", ids)
+	}
+}
@@ -0,0 +1,236 @@
+package linkage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"linkage/proto/job"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Receipt tells if a job submitted is accepted, Reason is set if it is rejected
+type Receipt struct {
+	ID       string `json:"id"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Submit implement jobServiceServer interface
+// producers push jobs to the engine of linkage through it,
+// the response has a receipt for each job in the order they were sent.
+// The stream ends once MaxSubmitJobs jobs are received, so receipts are not kept
+// without bound, the jobs sent after them have no receipt and should be submitted again
+func (s *Server) Submit(stream job.Service_SubmitServer) error {
+	if s.submit == nil {
+		return status.Error(codes.Unimplemented, "submit is not supported by the server")
+	}
+	if s.shouldClose() {
+		return status.Errorf(codes.Aborted, "server is closing")
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	id, err := s.authenticate(stream.Context(), req.GetPassphrase().GetCode())
+	if err != nil {
+		log.Errorf("authenticate fail, error: %v", err)
+		return err
+	}
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	addr := peerAddr(stream.Context())
+	log.WithFields(log.Fields{
+		"peer":     addr,
+		"identity": id.Name,
+	}).Info("producer submits jobs")
+
+	max := s.cfg.MaxSubmitJobs
+	if max < 1 {
+		max = defaultMaxSubmitJobs
+	}
+
+	var receipts []*job.Receipt
+	for {
+		if gj := req.GetJob(); gj != nil {
+			rc := s.accept(gj, addr)
+			receipts = append(receipts, &job.Receipt{
+				Id:       rc.ID,
+				Accepted: rc.Accepted,
+				Reason:   rc.Reason,
+			})
+		}
+
+		if len(receipts) >= max {
+			log.WithFields(log.Fields{
+				"peer": addr,
+				"jobs": len(receipts),
+			}).Info("submit stream ends at max jobs")
+			return stream.SendAndClose(&job.SubmitResponse{
+				Receipts: receipts,
+			})
+		}
+
+		req, err = stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&job.SubmitResponse{
+				Receipts: receipts,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// accept decompresses the job then hands it to linkage
+func (s *Server) accept(gj *job.Job, source string) Receipt {
+	err := decompressJob(gj, s.cfg.MaxJobSize)
+	if err != nil {
+		s.metrics.JobDropped("decompress")
+		return Receipt{
+			ID:     gj.GetId(),
+			Reason: err.Error(),
+		}
+	}
+	return s.acceptJob(toLinkageJob(gj), source)
+}
+
+// acceptJob hands the job to linkage and returns the receipt
+func (s *Server) acceptJob(j *Job, source string) Receipt {
+	if j.ID == "" {
+		j.ID = newJobID()
+	}
+	if j.CreatedAt.IsZero() {
+		j.CreatedAt = time.Now()
+	}
+
+	err := s.submit(j, source)
+	if err != nil {
+		return Receipt{
+			ID:     j.ID,
+			Reason: err.Error(),
+		}
+	}
+	return Receipt{
+		ID:       j.ID,
+		Accepted: true,
+	}
+}
+
+// submit hands the job pushed by producer to engine like jobs from upstreams
+func (s *Linkage) submit(j *Job, source string) error {
+	if s.isDraining() {
+		return errClosing
+	}
+
+	s.metrics.JobReceived(peerHost(source))
+	span := startSpan("linkage.submit", j.Trace, map[string]string{
+		"job.id":   j.ID,
+		"producer": source,
+	})
+	j.Trace = span.Context
+
+	err := s.take(j, source)
+	span.end(s.exporter, err)
+	return err
+}
+
+// Submit pushes the jobs to the server and returns their receipts in order,
+// the client dials the server for it if the stream is not built.
+// The jobs left without receipt when the server ends the stream are submitted in another one
+func (s *Client) Submit(jobs ...*Job) ([]Receipt, error) {
+	return s.SubmitContext(context.Background(), jobs...)
+}
+
+// SubmitContext is Submit canceled with ctx
+func (s *Client) SubmitContext(ctx context.Context, jobs ...*Job) ([]Receipt, error) {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+
+	if conn == nil {
+		c, err := s.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+		conn = c
+	}
+
+	var receipts []Receipt
+	for {
+		rs, err := s.submit(ctx, conn, jobs)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, rs...)
+		if len(rs) >= len(jobs) {
+			return receipts, nil
+		}
+		if len(rs) == 0 {
+			return nil, errNoReceipt
+		}
+		jobs = jobs[len(rs):]
+	}
+}
+
+// errNoReceipt fails Submit if the server ends the stream without receipt of the jobs
+var errNoReceipt = errors.New("no receipt of the jobs submitted")
+
+// submit pushes the jobs in a stream, the server may end it before all jobs are received
+func (s *Client) submit(ctx context.Context, conn *grpc.ClientConn, jobs []*Job) ([]Receipt, error) {
+	stream, err := job.NewServiceClient(conn).Submit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pass := &job.Passphrase{
+		Code: s.info.ConnCode,
+	}
+	if len(jobs) == 0 {
+		err = stream.Send(&job.SubmitRequest{
+			Passphrase: pass,
+		})
+	}
+	for i, j := range jobs {
+		req := &job.SubmitRequest{
+			Job: toGRPCJob(j),
+		}
+		if i == 0 {
+			req.Passphrase = pass
+		}
+
+		err = stream.Send(req)
+		if err != nil {
+			break
+		}
+	}
+	// the server ends the stream, the status is returned by CloseAndRecv
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]Receipt, 0, len(resp.GetReceipts()))
+	for _, r := range resp.GetReceipts() {
+		receipts = append(receipts, Receipt{
+			ID:       r.GetId(),
+			Accepted: r.GetAccepted(),
+			Reason:   r.GetReason(),
+		})
+	}
+	return receipts, nil
+}
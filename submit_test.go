@@ -0,0 +1,112 @@
+package linkage
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestSubmit(t *testing.T) {
+	e := newCollectEngine()
+	addr := freeAddr(t)
+	l, err := InitLinkage(addr, e, nil, func(code Code) bool { return code == "yo" }, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Run()
+	defer l.Stop()
+	waitListening(t, addr)
+
+	submit := func(code Code, jobs ...*Job) ([]Receipt, error) {
+		c, err := InitClient(&DialInfo{
+			ConnCode: code,
+			Addr:     addr,
+			Opts:     []grpc.DialOption{grpc.WithInsecure()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Submit(jobs...)
+	}
+
+	if _, err := submit("wrong", CreateJob("a", nil)); err == nil {
+		t.Fatal("jobs submitted with wrong passcode")
+	}
+
+	expired := CreateJob("expired", nil)
+	expired.Deadline = time.Now().Add(-time.Second)
+	jobs := []*Job{CreateJob("a", nil), expired, CreateJob("b", nil)}
+	receipts, err := submit("yo", jobs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a receipt for each job in order, the expired one is rejected
+	if len(receipts) != len(jobs) {
+		t.Fatalf("got %v receipts, want %v", len(receipts), len(jobs))
+	}
+	for i, r := range receipts {
+		accepted := jobs[i] != expired
+		if r.ID != jobs[i].ID || r.Accepted != accepted || (r.Reason == "") != accepted {
+			t.Errorf("receipt %v is %+v, want accepted %v", i, r, accepted)
+		}
+	}
+
+	for _, want := range []string{"a", "b"} {
+		j := e.wait(t, time.Second)
+		if j.Payload != want || j.Metadata[MetaSource] == "" {
+			t.Fatalf("engine got %q from %q, want %q from the producer", j.Payload, j.Metadata[MetaSource], want)
+		}
+	}
+}
+
+func TestSubmitMax(t *testing.T) {
+	e := newCollectEngine()
+	addr := freeAddr(t)
+	l, err := InitLinkage(addr, e, nil, func(Code) bool { return true }, nil, nil, WithMaxSubmitJobs(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Run()
+	defer l.Stop()
+	waitListening(t, addr)
+
+	c, err := InitClient(&DialInfo{
+		Addr: addr,
+		Opts: []grpc.DialOption{grpc.WithInsecure()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the jobs over the max of a stream are submitted in others
+	var jobs []*Job
+	for _, p := range []string{"a", "b", "c", "d", "e"} {
+		jobs = append(jobs, CreateJob(p, nil))
+	}
+	receipts, err := c.Submit(jobs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != len(jobs) {
+		t.Fatalf("got %v receipts, want %v", len(receipts), len(jobs))
+	}
+	for i, r := range receipts {
+		if r.ID != jobs[i].ID || !r.Accepted {
+			t.Errorf("receipt %v is %+v, want %v accepted", i, r, jobs[i].ID)
+		}
+	}
+
+	// each job is taken once
+	for _, j := range jobs {
+		if got := e.wait(t, time.Second); got.ID != j.ID {
+			t.Fatalf("engine got %v, want %v", got.Payload, j.Payload)
+		}
+	}
+	select {
+	case j := <-e.got:
+		t.Fatalf("engine got %v again", j.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,30 @@
+package linkage
+
+import "strings"
+
+// MatchTopic reports whether the routing key matches the topic filter.
+// Words in key and filter are separated by dot,
+// "*" in filter matches exactly one word and "#" matches zero or more words
+func MatchTopic(filter string, key string) bool {
+	return matchWords(strings.Split(filter, "."), strings.Split(key, "."))
+}
+
+func matchWords(filter []string, key []string) bool {
+	if len(filter) == 0 {
+		return len(key) == 0
+	}
+
+	switch filter[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchWords(filter[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && matchWords(filter[1:], key[1:])
+	default:
+		return len(key) > 0 && filter[0] == key[0] && matchWords(filter[1:], key[1:])
+	}
+}
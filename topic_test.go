@@ -0,0 +1,38 @@
+package linkage
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	cases := []struct {
+		filter string
+		key    string
+		want   bool
+	}{
+		{"orders.new", "orders.new", true},
+		{"orders.new", "orders.old", false},
+		{"orders", "orders.new", false},
+		{"orders.*", "orders.new", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.new.eu", false},
+		{"*.new", "orders.new", true},
+		{"*.*", "orders", false},
+		{"orders.#", "orders", true},
+		{"orders.#", "orders.new", true},
+		{"orders.#", "orders.new.eu", true},
+		{"orders.#", "users.new", false},
+		{"#", "orders.new.eu", true},
+		{"#.eu", "orders.new.eu", true},
+		{"#.eu", "orders.new.us", false},
+		{"orders.#.eu", "orders.eu", true},
+		{"orders.#.eu", "orders.new.big.eu", true},
+		{"orders.*.#", "orders", false},
+		{"orders.*.#", "orders.new", true},
+	}
+
+	for _, c := range cases {
+		got := MatchTopic(c.filter, c.key)
+		if got != c.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", c.filter, c.key, got, c.want)
+		}
+	}
+}
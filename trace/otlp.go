@@ -0,0 +1,275 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"linkage"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultOTLPEndpoint is the traces endpoint of OTLP/HTTP collector on local host
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+
+const (
+	otlpBatchSize    = 512
+	otlpQueueSize    = 4096
+	otlpInterval     = 5 * time.Second
+	otlpStatusErr    = 2
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+)
+
+// OTLP implements linkage.SpanExporter and posts spans to OTLP/HTTP collector in json,
+// spans are sent in batches and dropped if the queue is full
+type OTLP struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	spans    chan *linkage.Span
+	flush    chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// InitOTLP returns an OTLP posts to endpoint, service is the service.name of resource.
+// It posts to DefaultOTLPEndpoint if endpoint is empty
+func InitOTLP(endpoint, service string) *OTLP {
+	if endpoint == "" {
+		endpoint = DefaultOTLPEndpoint
+	}
+
+	o := &OTLP{
+		endpoint: endpoint,
+		service:  service,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		spans: make(chan *linkage.Span, otlpQueueSize),
+		flush: make(chan chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go o.loop()
+	return o
+}
+
+// ExportSpan implements linkage.SpanExporter
+func (o *OTLP) ExportSpan(s *linkage.Span) {
+	select {
+	case o.spans <- s:
+	default:
+		log.Warn("otlp queue is full, drop span")
+	}
+}
+
+// Flush posts the spans in queue
+func (o *OTLP) Flush() {
+	done := make(chan struct{})
+	select {
+	case o.flush <- done:
+		<-done
+	case <-o.done:
+	}
+}
+
+// Close posts the spans in queue and stops
+func (o *OTLP) Close() error {
+	o.once.Do(func() {
+		close(o.stop)
+	})
+	<-o.done
+	return nil
+}
+
+func (o *OTLP) loop() {
+	defer close(o.done)
+
+	ticker := time.NewTicker(otlpInterval)
+	defer ticker.Stop()
+
+	var batch []*linkage.Span
+	for {
+		select {
+		case s := <-o.spans:
+			batch = append(batch, s)
+			if len(batch) >= otlpBatchSize {
+				o.post(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			o.post(batch)
+			batch = nil
+		case done := <-o.flush:
+			batch = o.drain(batch)
+			o.post(batch)
+			batch = nil
+			close(done)
+		case <-o.stop:
+			o.post(o.drain(batch))
+			return
+		}
+	}
+}
+
+// drain takes all spans in queue
+func (o *OTLP) drain(batch []*linkage.Span) []*linkage.Span {
+	for {
+		select {
+		case s := <-o.spans:
+			batch = append(batch, s)
+		default:
+			return batch
+		}
+	}
+}
+
+func (o *OTLP) post(batch []*linkage.Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(o.request(batch))
+	if err != nil {
+		log.Errorf("encode spans fail, error: %v", err)
+		return
+	}
+
+	resp, err := o.client.Post(o.endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		log.Errorf("post spans fail, error: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Errorf("post spans fail, status: %v", resp.Status)
+	}
+}
+
+// OTLP/HTTP json, ids are hex and times are strings of unix nano
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (o *OTLP) request(batch []*linkage.Span) *otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		sp := otlpSpan{
+			TraceID:           fmt.Sprintf("%x", s.Context.TraceID),
+			SpanID:            fmt.Sprintf("%x", s.Context.SpanID),
+			Name:              s.Name,
+			Kind:              spanKind(s.Name),
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        keyValues(s.Attributes),
+		}
+		if s.Parent != [8]byte{} {
+			sp.ParentSpanID = fmt.Sprintf("%x", s.Parent)
+		}
+		if s.Err != nil {
+			sp.Status = &otlpStatus{
+				Code:    otlpStatusErr,
+				Message: s.Err.Error(),
+			}
+		}
+		spans = append(spans, sp)
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: keyValues(map[string]string{
+						"service.name": o.service,
+					}),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{
+							Name: "linkage",
+						},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+// spanKind is client for receiving jobs and server for sending jobs,
+// as the receiver asks jobs from the sender
+func spanKind(name string) int {
+	switch name {
+	case "linkage.receive":
+		return otlpKindClient
+	case "linkage.send":
+		return otlpKindServer
+	default:
+		return otlpKindInternal
+	}
+}
+
+func keyValues(attrs map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{
+			Key: k,
+			Value: otlpValue{
+				StringValue: attrs[k],
+			},
+		})
+	}
+	return kvs
+}
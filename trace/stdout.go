@@ -0,0 +1,67 @@
+// Package trace exports linkage spans to stdout or an OTLP collector
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"linkage"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stdout implements linkage.SpanExporter and writes spans as json lines
+type Stdout struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// InitStdout returns a Stdout writes to w, it writes to os.Stdout if w is nil
+func InitStdout(w io.Writer) *Stdout {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	return &Stdout{
+		w: w,
+	}
+}
+
+type stdoutSpan struct {
+	Name       string            `json:"name"`
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Start      time.Time         `json:"start"`
+	DurationMs float64           `json:"duration_ms"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// ExportSpan implements linkage.SpanExporter
+func (s *Stdout) ExportSpan(span *linkage.Span) {
+	ss := stdoutSpan{
+		Name:       span.Name,
+		TraceID:    fmt.Sprintf("%x", span.Context.TraceID),
+		SpanID:     fmt.Sprintf("%x", span.Context.SpanID),
+		Start:      span.Start,
+		DurationMs: float64(span.End.Sub(span.Start)) / float64(time.Millisecond),
+		Attributes: span.Attributes,
+	}
+	if span.Parent != [8]byte{} {
+		ss.ParentID = fmt.Sprintf("%x", span.Parent)
+	}
+	if span.Err != nil {
+		ss.Error = span.Err.Error()
+	}
+
+	b, err := json.Marshal(ss)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(append(b, '\n'))
+}
@@ -0,0 +1,158 @@
+package linkage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MetaTraceParent is the metadata key of the W3C traceparent of a job on the stream
+const MetaTraceParent = "traceparent"
+
+// SpanContext identifies a span in a trace, it is zero if the job is not traced
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+var errTraceParent = errors.New("invalid traceparent")
+
+// ParseTraceParent parses W3C traceparent "00-<trace id>-<span id>-<flags>"
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, errTraceParent
+	}
+	// version 00 has exactly 4 parts, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, errTraceParent
+	}
+
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return sc, errTraceParent
+	}
+
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return sc, errTraceParent
+	}
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, errTraceParent
+	}
+	return sc, nil
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// IsValid returns true if trace id and span id are not all zero
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// String returns the W3C traceparent, it is empty if the context is not valid
+func (sc SpanContext) String() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("00-%x-%x-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// MarshalText implements encoding.TextMarshaler, so the job in store keeps its trace
+func (sc SpanContext) MarshalText() ([]byte, error) {
+	return []byte(sc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (sc *SpanContext) UnmarshalText(b []byte) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		*sc = SpanContext{}
+		return nil
+	}
+
+	parsed, err := ParseTraceParent(string(b))
+	if err != nil {
+		return err
+	}
+	*sc = parsed
+	return nil
+}
+
+// Span is a timed operation on a job
+type Span struct {
+	Name       string
+	Context    SpanContext
+	Parent     [8]byte
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Err        error
+}
+
+// SpanExporter exports ended spans, see package trace for stdout and OTLP
+type SpanExporter interface {
+	// ExportSpan is called when a span ends, it should not block
+	ExportSpan(s *Span)
+}
+
+// nopExporter is the default SpanExporter which exports nothing
+type nopExporter struct{}
+
+func (nopExporter) ExportSpan(*Span) {}
+
+func exporterOrNop(e SpanExporter) SpanExporter {
+	if e == nil {
+		return nopExporter{}
+	}
+	return e
+}
+
+// startSpan starts a span as the child of parent,
+// it starts a new trace if parent is not valid
+func startSpan(name string, parent SpanContext, attrs map[string]string) *Span {
+	sc := parent
+	if !sc.IsValid() {
+		randomBytes(sc.TraceID[:])
+		sc.Flags = 0x01 // sampled
+	}
+	randomBytes(sc.SpanID[:])
+
+	s := &Span{
+		Name:       name,
+		Context:    sc,
+		Start:      time.Now(),
+		Attributes: attrs,
+	}
+	if parent.IsValid() {
+		s.Parent = parent.SpanID
+	}
+	return s
+}
+
+// end ends the span and exports it
+func (s *Span) end(e SpanExporter, err error) {
+	s.End = time.Now()
+	s.Err = err
+	e.ExportSpan(s)
+}
+
+func randomBytes(b []byte) {
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+}
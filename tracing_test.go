@@ -0,0 +1,42 @@
+package linkage
+
+import "testing"
+
+func TestParseTraceParent(t *testing.T) {
+	cases := []struct {
+		in    string
+		valid bool
+		flags byte
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, 1},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, 0},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, 1},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, 0},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, 0},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, 0},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", false, 0},
+		{"", false, 0},
+	}
+
+	for _, c := range cases {
+		sc, err := ParseTraceParent(c.in)
+		if (err == nil) != c.valid {
+			t.Errorf("ParseTraceParent(%q) error = %v, want valid %v", c.in, err, c.valid)
+			continue
+		}
+		if !c.valid {
+			continue
+		}
+		if sc.Flags != c.flags {
+			t.Errorf("ParseTraceParent(%q) flags = %v, want %v", c.in, sc.Flags, c.flags)
+		}
+		// version 00 is written back as it is
+		if c.in[:2] == "00" && sc.String() != c.in {
+			t.Errorf("ParseTraceParent(%q).String() = %q", c.in, sc.String())
+		}
+	}
+}
@@ -1,32 +1,72 @@
 package linkage
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
 
-// Waiting define the rule of wait time between each retry
-type Waiting func() error
+// Waiting define the rule of wait time between each retry,
+// it returns ctx.Err() once ctx is canceled
+type Waiting func(ctx context.Context) error
 
-// WaitFactory generate waiting function
+// WaitFactory generate waiting function, the wait is init seconds
+// multiplied by grow on each call, up to a minute.
+// It gives up after maxRetry calls, never gives up if maxRetry is less than 1
 func WaitFactory(init int, grow int, maxRetry int) Waiting {
 	if init < 1 {
 		init = 1
 	}
 	if grow < 1 {
-		init = 1
+		grow = 1
 	}
 
-	wt := init
+	wt := time.Duration(init) * time.Second
 	attempt := 0
-	return func() error {
-		if attempt == maxRetry {
+	return func(ctx context.Context) error {
+		if maxRetry > 0 && attempt == maxRetry {
 			return fmt.Errorf("reach max attempt")
 		}
 
-		wt = wt * grow
-		time.Sleep(time.Duration(wt) * time.Second)
+		wt = wt * time.Duration(grow)
+		if wt > maxBackoff {
+			wt = maxBackoff
+		}
 		attempt++
+		return sleep(ctx, wt)
+	}
+}
+
+// Backoff generates waiting function never gives up,
+// it waits init at first and twice as long after each call until max
+func Backoff(init, max time.Duration) Waiting {
+	if init <= 0 {
+		init = time.Second
+	}
+	if max < init {
+		max = init
+	}
+
+	wt := init
+	return func(ctx context.Context) error {
+		err := sleep(ctx, wt)
+		wt = wt * 2
+		if wt > max {
+			wt = max
+		}
+		return err
+	}
+}
+
+// sleep waits d, it returns ctx.Err() if ctx is canceled before
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
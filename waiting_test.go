@@ -0,0 +1,61 @@
+package linkage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitingContext(t *testing.T) {
+	cases := []struct {
+		name string
+		wait Waiting
+	}{
+		{"backoff", Backoff(time.Hour, time.Hour)},
+		{"factory", WaitFactory(3600, 1, 1)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := tc.wait(ctx)
+			if err != context.DeadlineExceeded {
+				t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Fatalf("waited %v after ctx canceled", d)
+			}
+		})
+	}
+}
+
+func TestWaitFactoryRetry(t *testing.T) {
+	cases := []struct {
+		name     string
+		maxRetry int
+		calls    int
+		giveUp   bool
+	}{
+		{"under max", 3, 3, false},
+		{"over max", 3, 4, true},
+		{"unlimited", 0, 100, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// canceled ctx ends each wait at once
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			wait := WaitFactory(0, 0, tc.maxRetry)
+			var err error
+			for i := 0; i < tc.calls; i++ {
+				err = wait(ctx)
+			}
+			if giveUp := err != context.Canceled; giveUp != tc.giveUp {
+				t.Fatalf("got error %v after %v calls, want give up %v", err, tc.calls, tc.giveUp)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package linkage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"linkage/proto/job"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// websocket streams jobs to the client over websocket, like Ask.
+// The passcode is sent by query parameter "code" since browsers can not set headers,
+// it is written in access logs of proxies like any url, so use short lived codes for browsers.
+// topics by "topic". Jobs are acked once they are written unless "ack=manual",
+// then the client acks each job and grants credit by wsFeedback,
+// "window" is the credit granted when the stream opens
+func (g *gateway) websocket(w http.ResponseWriter, r *http.Request) {
+	if g.server.shouldClose() {
+		httpError(w, http.StatusServiceUnavailable, "server is closing")
+		return
+	}
+
+	// authenticate before upgrading, so the status can tell
+	ctx := grpcContext(r)
+	code := requestCode(r)
+	_, err := g.server.authenticate(ctx, code)
+	if err != nil {
+		httpStatusError(w, err)
+		return
+	}
+
+	q := r.URL.Query()
+	pass := &job.Feedback{
+		Passphrase: &job.Passphrase{
+			Code:   code,
+			Topics: q["topic"],
+		},
+	}
+	manual := q.Get("ack") == "manual"
+	if manual {
+		window, _ := strconv.Atoi(q.Get("window"))
+		if window > 0 {
+			pass.Credit = uint32(window)
+		}
+	}
+
+	srv := websocket.Server{
+		// the origin is checked by the gateway before upgrading
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			stream := newWSStream(ctx, conn, manual)
+			stream.pass = pass
+			go stream.read()
+
+			err := g.server.Ask(stream)
+			if status.Code(err) != codes.Canceled {
+				stream.fail(err)
+			}
+			stream.close()
+		},
+	}
+	srv.ServeHTTP(w, r)
+}
+
+// wsFeedback is sent by the client to ack a job when it acks manually
+type wsFeedback struct {
+	Ack *struct {
+		ID     string `json:"id"`
+		Nack   bool   `json:"nack"`
+		Reason string `json:"reason"`
+	} `json:"ack"`
+	Credit uint32 `json:"credit"`
+}
+
+// wsStream adapts the websocket connection to job.Service_AskServer,
+// each job is sent as a text message in json.
+// The stream ends when the client closes the socket
+type wsStream struct {
+	streamHeader
+	ctx    context.Context
+	conn   *websocket.Conn
+	manual bool
+	pass   *job.Feedback
+	acks   *ackQueue
+	fbs    chan *job.Feedback
+	closed chan struct{}
+	done   chan struct{}
+}
+
+func newWSStream(ctx context.Context, conn *websocket.Conn, manual bool) *wsStream {
+	return &wsStream{
+		ctx:    ctx,
+		conn:   conn,
+		manual: manual,
+		acks:   newAckQueue(),
+		fbs:    make(chan *job.Feedback),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// read reads the messages from the client until the socket is closed,
+// they are ignored unless the client acks manually
+func (s *wsStream) read() {
+	defer close(s.closed)
+	for {
+		var msg wsFeedback
+		err := websocket.JSON.Receive(s.conn, &msg)
+		if err != nil {
+			if isJSONError(err) {
+				continue
+			}
+			return
+		}
+		if !s.manual {
+			continue
+		}
+
+		fb := &job.Feedback{
+			Credit: msg.Credit,
+		}
+		if msg.Ack != nil {
+			fb.Ack = &job.Ack{
+				Id:     msg.Ack.ID,
+				Nack:   msg.Ack.Nack,
+				Reason: msg.Ack.Reason,
+			}
+		}
+
+		select {
+		case s.fbs <- fb:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// close stops reading after Ask returns
+func (s *wsStream) close() {
+	close(s.done)
+}
+
+// Send writes the job, then acks it unless the client acks manually
+func (s *wsStream) Send(gj *job.Job) error {
+	err := decompressJob(gj, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(toLinkageJob(gj))
+	if err != nil {
+		return err
+	}
+
+	err = websocket.Message.Send(s.conn, string(b))
+	if err != nil {
+		return err
+	}
+
+	if !s.manual {
+		s.acks.push(gj.GetId())
+	}
+	return nil
+}
+
+// fail tells the client why the stream ends
+func (s *wsStream) fail(err error) {
+	websocket.Message.Send(s.conn, string(errorJSON(err)))
+}
+
+// Recv returns the passphrase first, then the acks,
+// it returns io.EOF when the socket is closed
+func (s *wsStream) Recv() (*job.Feedback, error) {
+	if s.pass != nil {
+		fb := s.pass
+		s.pass = nil
+		return fb, nil
+	}
+
+	fb, ok := s.acks.pop(s.closed, s.fbs)
+	if !ok {
+		return nil, io.EOF
+	}
+	return fb, nil
+}
+
+func (s *wsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *wsStream) SendMsg(m interface{}) error {
+	return s.Send(m.(*job.Job))
+}
+
+func (s *wsStream) RecvMsg(m interface{}) error {
+	fb, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	*m.(*job.Feedback) = *fb
+	return nil
+}
+
+// isJSONError returns true if the message is not a wsFeedback,
+// the message is skipped and the socket stays open
+func isJSONError(err error) bool {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+package linkage
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// dialWS opens the websocket stream of the gateway with query
+func dialWS(t *testing.T, url string, query string) *websocket.Conn {
+	ws := "ws" + strings.TrimPrefix(url, "http") + "/jobs/ws?" + query
+	conn, err := websocket.Dial(ws, "", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receiveWS returns the next job, it returns nil if nothing comes before timeout
+func receiveWS(t *testing.T, conn *websocket.Conn, timeout time.Duration) *Job {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	var msg string
+	err := websocket.Message.Receive(conn, &msg)
+	if err != nil {
+		return nil
+	}
+
+	var j Job
+	err = json.Unmarshal([]byte(msg), &j)
+	if err != nil || j.ID == "" {
+		t.Fatalf("got message %v", msg)
+	}
+	return &j
+}
+
+func TestWebsocketAutoAck(t *testing.T) {
+	url := serveGateway(t, newRelayEngine())
+	conn := dialWS(t, url, "code=yo")
+
+	_, sr := postJobs(t, url, "yo", `[{"payload":"a"},{"payload":"b"}]`)
+	for i, want := range []string{"a", "b"} {
+		j := receiveWS(t, conn, 2*time.Second)
+		if j == nil || j.Payload != want || j.ID != sr.Receipts[i].ID {
+			t.Fatalf("got job %+v, want %v", j, want)
+		}
+	}
+}
+
+func TestWebsocketManualAck(t *testing.T) {
+	url := serveGateway(t, newRelayEngine())
+	conn := dialWS(t, url, "code=yo&ack=manual&window=1")
+
+	postJobs(t, url, "yo", `[{"payload":"a"},{"payload":"b"}]`)
+	first := receiveWS(t, conn, 2*time.Second)
+	if first == nil || first.Payload != "a" {
+		t.Fatalf("got job %+v, want a", first)
+	}
+	if j := receiveWS(t, conn, 300*time.Millisecond); j != nil {
+		t.Fatalf("job %v sent beyond the window", j.Payload)
+	}
+
+	// nack the first one and grant credit for the next
+	err := websocket.JSON.Send(conn, map[string]interface{}{
+		"ack":    map[string]interface{}{"id": first.ID, "nack": true, "reason": "later"},
+		"credit": 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		j := receiveWS(t, conn, 2*time.Second)
+		if j == nil {
+			t.Fatalf("got %v, want a again and b", got)
+		}
+		got[j.Payload] = true
+	}
+	if !got["a"] || !got["b"] {
+		t.Fatalf("got %v, want a again and b", got)
+	}
+}
+
+func TestWebsocketAuth(t *testing.T) {
+	url := serveGateway(t, newRelayEngine())
+	ws := "ws" + strings.TrimPrefix(url, "http") + "/jobs/ws?code=no"
+	if conn, err := websocket.Dial(ws, "", url); err == nil {
+		conn.Close()
+		t.Fatal("websocket opened with wrong code")
+	}
+}